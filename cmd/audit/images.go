@@ -0,0 +1,393 @@
+// Package audit implements auditing files of a datafile's source.
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "github.com/jdeng/goheif"
+	"github.com/opentouristics/database-tools/exif"
+	"github.com/opentouristics/database-tools/models"
+	_ "golang.org/x/image/webp"
+)
+
+// formats maps extensions of image files to names of their formats.
+var formats = map[string]string{
+	".jpg":  "jpeg",
+	".jpeg": "jpeg",
+	".png":  "png",
+	".webp": "webp",
+	".heic": "heic",
+}
+
+// Image is a single image file of a datafile's source.
+type Image struct {
+	Path   string `json:"path"`   // Relative to the datafile's root.
+	Kind   string `json:"kind"`   // "image" or "icon".
+	Tier   string `json:"tier"`   // "original" or "compressed".
+	Format string `json:"format"` // "jpeg", "png", "webp" or "heic".
+	Size   int64  `json:"size"`   // In bytes.
+	Width  int    `json:"width"`  // Of the upright image, according to its EXIF orientation.
+	Height int    `json:"height"`
+	Error  string `json:"error,omitempty"` // Why the image couldn't be decoded.
+}
+
+// Portrait returns true if the image is taller than wide. Photos are shown in
+// landscape, so portrait ones get cropped a lot.
+func (img Image) Portrait() bool {
+	return img.Height > img.Width
+}
+
+// Filter selects images to audit. Empty fields select everything.
+type Filter struct {
+	Kind string // "image" or "icon".
+	Tier string // "original" or "compressed".
+}
+
+// Budgets are limits which images must fit in. Zero fields are not checked.
+type Budgets struct {
+	MaxSize      int64 // Of a single image, in bytes.
+	MaxTotalSize int64 // Of all audited images, in bytes.
+	MaxWidth     int
+	MaxHeight    int
+	MinWidth     int
+}
+
+// Violation is an image which exceeds a budget or can't be decoded.
+type Violation struct {
+	Path    string `json:"path"` // Empty for the total size.
+	Message string `json:"message"`
+}
+
+// Bucket is a number of images with the same aspect ratio.
+type Bucket struct {
+	Ratio string  `json:"ratio"` // E.g. "4:3", or "1.29:1" if it's uncommon.
+	Value float64 `json:"value"` // Width divided by height.
+	Count int     `json:"count"`
+}
+
+// Report is the result of an audit of images.
+type Report struct {
+	Images     []Image     `json:"images"`
+	Histogram  []Bucket    `json:"histogram"`
+	Violations []Violation `json:"violations"`
+}
+
+// AuditImages audits images of region's datafile source selected by filter and
+// prints the report to stdout in format ("text", "csv" or "json"). Aspect
+// ratios are sorted by sortBy ("count" or "ratio"). It returns an error if any
+// image exceeds budgets or can't be decoded.
+func AuditImages(regionID string, filter Filter, budgets Budgets, format string, sortBy string) error {
+	if format != "text" && format != "csv" && format != "json" {
+		return fmt.Errorf("unknown format %#v (want text, csv or json)", format)
+	}
+	if sortBy != "count" && sortBy != "ratio" {
+		return fmt.Errorf("unknown sort order %#v (want count or ratio)", sortBy)
+	}
+
+	datafileDir := filepath.Join("datafiles", "datafile-"+regionID)
+	if _, err := os.Stat(datafileDir); err != nil {
+		return fmt.Errorf("stat datafile's directory: %w", err)
+	}
+
+	images, err := Scan(datafileDir, filter)
+	if err != nil {
+		return err
+	}
+
+	report := Report{
+		Images:     images,
+		Histogram:  Histogram(images, sortBy),
+		Violations: budgets.Check(images),
+	}
+
+	switch format {
+	case "json":
+		err = report.WriteJSON(os.Stdout)
+	case "csv":
+		err = report.WriteCSV(os.Stdout)
+	default:
+		report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(report.Violations) > 0 {
+		return fmt.Errorf("%d images exceed budgets or can't be decoded", len(report.Violations))
+	}
+
+	return nil
+}
+
+// Scan returns images in "images/original" and "images/compressed"
+// directories of the datafile at datafileDir, selected by filter and sorted by
+// path. Images which can't be decoded are returned with an error.
+func Scan(datafileDir string, filter Filter) ([]Image, error) {
+	images := make([]Image, 0)
+	err := filepath.WalkDir(datafileDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		tier := filepath.Base(dir)
+		if filepath.Base(filepath.Dir(dir)) != "images" || (tier != "original" && tier != "compressed") {
+			return nil
+		}
+		if d.Name() == models.FocusFile || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		kind := "image"
+		if strings.HasPrefix(d.Name(), "ic_") {
+			kind = "icon"
+		}
+
+		if (filter.Kind != "" && filter.Kind != kind) || (filter.Tier != "" && filter.Tier != tier) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(datafileDir, path)
+		if err != nil {
+			return err
+		}
+
+		img := Image{Path: filepath.ToSlash(relPath), Kind: kind, Tier: tier, Size: info.Size()}
+		img.Format = formats[strings.ToLower(filepath.Ext(path))]
+		if img.Format == "" {
+			img.Error = "unsupported format"
+		} else {
+			img.Width, img.Height, err = uprightSize(path)
+			if err != nil {
+				img.Error = err.Error()
+			}
+		}
+
+		images = append(images, img)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %s: %w", datafileDir, err)
+	}
+
+	return images, nil
+}
+
+// uprightSize returns dimensions of the image at path after it's rotated
+// according to its EXIF orientation.
+func uprightSize(path string) (int, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, fmt.Errorf("decode: %v", err)
+	}
+
+	metadata, err := exif.Read(path)
+	if err == nil && metadata.Orientation >= 5 {
+		return config.Height, config.Width, nil
+	}
+
+	return config.Width, config.Height, nil
+}
+
+// Check returns images which exceed budgets b or can't be decoded.
+func (b Budgets) Check(images []Image) []Violation {
+	violations := make([]Violation, 0)
+	add := func(img Image, format string, args ...any) {
+		violations = append(violations, Violation{Path: img.Path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var total int64
+	for _, img := range images {
+		total += img.Size
+
+		if img.Error != "" {
+			add(img, "can't be decoded: %s", img.Error)
+			continue
+		}
+
+		if b.MaxSize != 0 && img.Size > b.MaxSize {
+			add(img, "size %s is over %s", formatMB(img.Size), formatMB(b.MaxSize))
+		}
+		if b.MaxWidth != 0 && img.Width > b.MaxWidth {
+			add(img, "width %d px is over %d px", img.Width, b.MaxWidth)
+		}
+		if b.MaxHeight != 0 && img.Height > b.MaxHeight {
+			add(img, "height %d px is over %d px", img.Height, b.MaxHeight)
+		}
+		if b.MinWidth != 0 && img.Width < b.MinWidth {
+			add(img, "width %d px is under %d px", img.Width, b.MinWidth)
+		}
+	}
+
+	if b.MaxTotalSize != 0 && total > b.MaxTotalSize {
+		violations = append(violations, Violation{Message: fmt.Sprintf("total size %s is over %s", formatMB(total), formatMB(b.MaxTotalSize))})
+	}
+
+	return violations
+}
+
+// commonRatios are aspect ratios which are named instead of given as numbers.
+var commonRatios = [][2]int{{1, 1}, {4, 3}, {3, 2}, {16, 9}, {5, 4}, {21, 9}, {3, 4}, {2, 3}, {9, 16}, {4, 5}}
+
+// ratioName returns name of the aspect ratio of a w x h image.
+func ratioName(w int, h int) string {
+	value := float64(w) / float64(h)
+	for _, ratio := range commonRatios {
+		if math.Abs(value/(float64(ratio[0])/float64(ratio[1]))-1) < 0.01 {
+			return fmt.Sprintf("%d:%d", ratio[0], ratio[1])
+		}
+	}
+
+	return strconv.FormatFloat(value, 'f', 2, 64) + ":1"
+}
+
+// Histogram counts decoded images by their aspect ratio. Buckets are sorted by
+// sortBy: "count" (most common first) or "ratio" (widest first).
+func Histogram(images []Image, sortBy string) []Bucket {
+	buckets := make([]Bucket, 0)
+	index := make(map[string]int)
+	for _, img := range images {
+		if img.Error != "" || img.Height == 0 {
+			continue
+		}
+
+		name := ratioName(img.Width, img.Height)
+		i, ok := index[name]
+		if !ok {
+			i = len(buckets)
+			index[name] = i
+			buckets = append(buckets, Bucket{Ratio: name, Value: math.Round(float64(img.Width)/float64(img.Height)*100) / 100})
+		}
+		buckets[i].Count++
+	}
+
+	sort.SliceStable(buckets, func(i, j int) bool {
+		if sortBy == "ratio" || buckets[i].Count == buckets[j].Count {
+			return buckets[i].Value > buckets[j].Value
+		}
+		return buckets[i].Count > buckets[j].Count
+	})
+
+	return buckets
+}
+
+func formatMB(size int64) string {
+	return fmt.Sprintf("%.2f MB", float64(size)/1000/1000)
+}
+
+// WriteText writes counts of images by format, the aspect ratio histogram,
+// portrait images and violations to w.
+func (r *Report) WriteText(w io.Writer) {
+	counts := make(map[string]int)
+	var total int64
+	for _, img := range r.Images {
+		counts[img.Format]++
+		total += img.Size
+	}
+
+	fmt.Fprintf(w, "%d jpegs, %d pngs, %d webps, %d heics (%d total, %s)\n", counts["jpeg"], counts["png"], counts["webp"], counts["heic"], len(r.Images), formatMB(total))
+
+	if len(r.Histogram) > 0 {
+		fmt.Fprintln(w, "\naspect ratios:")
+		for _, bucket := range r.Histogram {
+			bar := strings.Repeat("#", int(math.Ceil(40*float64(bucket.Count)/float64(len(r.Images)))))
+			fmt.Fprintf(w, "  %-8s %5d %s\n", bucket.Ratio, bucket.Count, bar)
+		}
+	}
+
+	portraits := make([]Image, 0)
+	for _, img := range r.Images {
+		if img.Kind == "image" && img.Portrait() {
+			portraits = append(portraits, img)
+		}
+	}
+
+	if len(portraits) > 0 {
+		fmt.Fprintf(w, "\n%d portrait images (they are cropped in the app):\n", len(portraits))
+		for _, img := range portraits {
+			fmt.Fprintf(w, "  %s (%dx%d)\n", img.Path, img.Width, img.Height)
+		}
+	}
+
+	if len(r.Violations) > 0 {
+		fmt.Fprintf(w, "\n%d violations:\n", len(r.Violations))
+		for _, v := range r.Violations {
+			if v.Path == "" {
+				fmt.Fprintf(w, "  %s\n", v.Message)
+			} else {
+				fmt.Fprintf(w, "  %s: %s\n", v.Path, v.Message)
+			}
+		}
+	}
+}
+
+// WriteCSV writes a row for every image to w.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"path", "kind", "tier", "format", "size", "width", "height", "aspect_ratio", "portrait", "error"})
+
+	for _, img := range r.Images {
+		ratio := ""
+		if img.Error == "" && img.Height != 0 {
+			ratio = ratioName(img.Width, img.Height)
+		}
+
+		writer.Write([]string{
+			img.Path,
+			img.Kind,
+			img.Tier,
+			img.Format,
+			strconv.FormatInt(img.Size, 10),
+			strconv.Itoa(img.Width),
+			strconv.Itoa(img.Height),
+			ratio,
+			strconv.FormatBool(img.Portrait()),
+			img.Error,
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the report as JSON to w.
+func (r *Report) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal report to JSON: %v", err)
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,76 @@
+package audit_test
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/cmd/audit"
+)
+
+func writePNG(t *testing.T, path string, w int, h int) {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	err = png.Encode(file, image.NewGray(image.Rect(0, 0, w, h)))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "sections", "01_z", "places", "kosciol", "images", "original")
+	writePNG(t, filepath.Join(original, "k1.png"), 400, 300)
+	writePNG(t, filepath.Join(original, "k2.png"), 300, 400)
+	writePNG(t, filepath.Join(original, "ic_kosciol.png"), 512, 512)
+	os.WriteFile(filepath.Join(original, "k3.jpg"), []byte("not a jpeg"), 0o644)
+	os.WriteFile(filepath.Join(original, "focus.json"), []byte("{}"), 0o644)
+
+	images, err := audit.Scan(dir, audit.Filter{Kind: "image"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, img := range images {
+		got = append(got, img.Path)
+	}
+	want := []string{
+		"sections/01_z/places/kosciol/images/original/k1.png",
+		"sections/01_z/places/kosciol/images/original/k2.png",
+		"sections/01_z/places/kosciol/images/original/k3.jpg",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("scanned images mismatch (-want +got):\n%s", diff)
+	}
+	if !images[1].Portrait() || images[2].Error == "" {
+		t.Errorf("got %+v, want k2 in portrait and k3 undecodable", images)
+	}
+
+	histogram := audit.Histogram(images, "ratio")
+	wantHistogram := []audit.Bucket{{Ratio: "4:3", Value: 1.33, Count: 1}, {Ratio: "3:4", Value: 0.75, Count: 1}}
+	if diff := cmp.Diff(wantHistogram, histogram); diff != "" {
+		t.Errorf("histogram mismatch (-want +got):\n%s", diff)
+	}
+
+	violations := audit.Budgets{MaxWidth: 350}.Check(images)
+	wantViolations := []audit.Violation{
+		{Path: "sections/01_z/places/kosciol/images/original/k1.png", Message: "width 400 px is over 350 px"},
+		{Path: "sections/01_z/places/kosciol/images/original/k3.jpg", Message: "can't be decoded: " + images[2].Error},
+	}
+	if diff := cmp.Diff(wantViolations, violations); diff != "" {
+		t.Errorf("violations mismatch (-want +got):\n%s", diff)
+	}
+}
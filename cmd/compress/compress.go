@@ -9,11 +9,42 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/opentouristics/database-tools/lockfile"
+	"github.com/opentouristics/database-tools/models"
 )
 
 // Compress takes a generated directory of region's datafile and creates a zip
-// archive out of it.
-func Compress(regionID string, verbose bool) error {
+// archive out of it. The same is done for the region's single-language pack in
+// every language in langs.
+//
+// The region is locked while it is compressed, so that generate can't change
+// it at the same time.
+func Compress(regionID string, langs []string, verbose bool) error {
+	lock, err := lockfile.AcquireRegion(regionID)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	err = compressPack(regionID, verbose)
+	if err != nil {
+		return err
+	}
+
+	for _, lang := range langs {
+		err = compressPack(models.PackID(regionID, lang), verbose)
+		if err != nil {
+			return fmt.Errorf("compress %s pack: %v", lang, err)
+		}
+	}
+
+	return nil
+}
+
+// compressPack creates a zip archive out of the generated directory of pack
+// packID.
+func compressPack(regionID string, verbose bool) error {
 	_, err := os.Stat("compressed/")
 	if os.IsNotExist(err) {
 		err = os.Mkdir("compressed", 0o755)
@@ -0,0 +1,134 @@
+// Package coverage implements reporting how completely texts of a datafile are
+// translated to each of its languages (see models.Measure).
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opentouristics/database-tools/models"
+)
+
+// Check measures translation coverage of region's datafile and prints it to
+// stdout in format ("text" or "json"). It returns an error if coverage of any
+// of required languages is below threshold percent. A required language
+// missing from the datafile has 0% coverage.
+func Check(regionID string, format string, required []string, threshold float64) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown format %#v (want text or json)", format)
+	}
+
+	datafileDir := filepath.Join("datafiles", "datafile-"+regionID)
+	if _, err := os.Stat(datafileDir); err != nil {
+		return fmt.Errorf("stat datafile's directory: %w", err)
+	}
+
+	coverage, err := models.Measure(os.DirFS(datafileDir))
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		err = WriteJSON(os.Stdout, &coverage)
+		if err != nil {
+			return err
+		}
+	} else {
+		WriteText(os.Stdout, &coverage)
+	}
+
+	failed := make([]string, 0)
+	for _, lang := range required {
+		if percent := coverage.Required(lang); percent < threshold {
+			failed = append(failed, fmt.Sprintf("%s (%.1f%%)", lang, percent))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("coverage below %.1f%%: %s", threshold, strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// WriteText writes coverage c of every language, in total and per group, and
+// the list of missing files to w.
+func WriteText(w io.Writer, c *models.Coverage) {
+	fmt.Fprintf(w, "%-24s", "")
+	for _, lang := range c.Languages {
+		fmt.Fprintf(w, "%8s", lang)
+	}
+	fmt.Fprintln(w)
+
+	row := func(name string, group string) {
+		fmt.Fprintf(w, "%-24s", name)
+		for _, lang := range c.Languages {
+			fmt.Fprintf(w, "%7.1f%%", c.Percent(lang, group))
+		}
+		fmt.Fprintln(w)
+	}
+
+	for _, group := range c.Groups {
+		row(group, group)
+	}
+	row("total", "")
+
+	missing := c.Missing()
+	if len(missing) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%d missing files:\n", len(missing))
+	for _, cell := range missing {
+		fmt.Fprintf(w, "  %s\n", cell.Path)
+	}
+}
+
+// WriteJSON writes coverage c of every language, in total and per group, and
+// the list of missing files as JSON to w.
+func WriteJSON(w io.Writer, c *models.Coverage) error {
+	type group struct {
+		Group   string             `json:"group"`
+		Percent map[string]float64 `json:"percent"`
+	}
+
+	output := struct {
+		Languages []string              `json:"languages"`
+		Percent   map[string]float64    `json:"percent"`
+		Groups    []group               `json:"groups"`
+		Missing   []models.CoverageCell `json:"missing"`
+	}{
+		Languages: c.Languages,
+		Percent:   make(map[string]float64),
+		Groups:    make([]group, 0, len(c.Groups)),
+		Missing:   c.Missing(),
+	}
+
+	if output.Languages == nil {
+		output.Languages = make([]string, 0)
+	}
+
+	for _, lang := range c.Languages {
+		output.Percent[lang] = c.Percent(lang, "")
+	}
+
+	for _, name := range c.Groups {
+		g := group{Group: name, Percent: make(map[string]float64)}
+		for _, lang := range c.Languages {
+			g.Percent[lang] = c.Percent(lang, name)
+		}
+		output.Groups = append(output.Groups, g)
+	}
+
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal coverage to JSON: %v", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,100 @@
+// Package diff implements comparing two versions of a datafile.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/opentouristics/database-tools/models"
+)
+
+// Diff loads datafiles from sources old and new (see models.Load), and prints changes
+// between them to stdout in format ("text" or "json").
+func Diff(old string, new string, format string, jobs int) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown format %#v (want text or json)", format)
+	}
+
+	oldDatafile, err := models.Load(old, jobs)
+	if err != nil {
+		return fmt.Errorf("load %s: %v", old, err)
+	}
+
+	newDatafile, err := models.Load(new, jobs)
+	if err != nil {
+		return fmt.Errorf("load %s: %v", new, err)
+	}
+
+	changes := models.Diff(&oldDatafile, &newDatafile)
+
+	if format == "json" {
+		return WriteJSON(os.Stdout, changes)
+	}
+
+	WriteText(os.Stdout, changes)
+	return nil
+}
+
+// maxValueLength is the length above which values are shortened in the text
+// output.
+const maxValueLength = 80
+
+// WriteText writes changes to w in a human-readable form.
+func WriteText(w io.Writer, changes []models.Change) {
+	symbols := map[models.ChangeKind]string{
+		models.Added:    "+",
+		models.Removed:  "-",
+		models.Modified: "~",
+	}
+
+	counts := make(map[models.ChangeKind]int)
+	for _, change := range changes {
+		counts[change.Kind]++
+
+		fmt.Fprintf(w, "%s %s %s\n", symbols[change.Kind], change.Entity, change.ID)
+		for _, field := range change.Fields {
+			name := field.Field
+			if field.Lang != "" {
+				name += " [" + field.Lang + "]"
+			}
+
+			fmt.Fprintf(w, "    %s: %q -> %q\n", name, shorten(field.Old), shorten(field.New))
+		}
+		for _, image := range change.ImagesAdded {
+			fmt.Fprintf(w, "    images: + %s\n", image)
+		}
+		for _, image := range change.ImagesRemoved {
+			fmt.Fprintf(w, "    images: - %s\n", image)
+		}
+	}
+
+	fmt.Fprintf(w, "%d added, %d removed, %d modified\n", counts[models.Added], counts[models.Removed], counts[models.Modified])
+}
+
+// WriteJSON writes changes as JSON to w.
+func WriteJSON(w io.Writer, changes []models.Change) error {
+	if changes == nil {
+		changes = make([]models.Change, 0)
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Changes []models.Change `json:"changes"`
+	}{changes}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal changes to JSON: %v", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+func shorten(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxValueLength {
+		return s
+	}
+
+	return string(runes[:maxValueLength-3]) + "..."
+}
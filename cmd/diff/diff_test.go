@@ -0,0 +1,29 @@
+package diff_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/opentouristics/database-tools/cmd/diff"
+	"github.com/opentouristics/database-tools/models"
+)
+
+func TestWriteText(t *testing.T) {
+	changes := []models.Change{
+		{Entity: "place", ID: "kosciol", Kind: models.Modified, Fields: []models.FieldChange{{Field: "name", Lang: "en", Old: "Church", New: strings.Repeat("a", 100)}}, ImagesAdded: []string{"kosciol_2"}},
+		{Entity: "place", ID: "palac", Kind: models.Added},
+	}
+
+	var buf bytes.Buffer
+	diff.WriteText(&buf, changes)
+
+	want := "~ place kosciol\n" +
+		"    name [en]: \"Church\" -> \"" + strings.Repeat("a", 77) + "...\"\n" +
+		"    images: + kosciol_2\n" +
+		"+ place palac\n" +
+		"1 added, 0 removed, 1 modified\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
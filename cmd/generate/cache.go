@@ -0,0 +1,163 @@
+package generate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/opentouristics/database-tools/models"
+)
+
+// cacheDir is where caches of generated datafiles are stored. It is outside of
+// the datafiles' directories, so it doesn't end up in zip archives.
+var cacheDir = filepath.Join("generated", ".cache")
+
+// cacheEntry describes the source file from which a file in the generated
+// directory was copied.
+type cacheEntry struct {
+	Source  string    `json:"source"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+}
+
+// cache remembers which source files were copied into the generated directory,
+// so that unchanged files don't have to be copied again.
+type cache struct {
+	// Keyed by destination path, relative to the generated directory.
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+func cachePath(regionID string) string {
+	return filepath.Join(cacheDir, regionID+".json")
+}
+
+// loadCache reads the cache of region's generated directory and removes it from
+// disk, so that a generation that fails halfway can't leave a cache that lies
+// about the generated directory. A missing cache is not an error.
+func loadCache(regionID string) (*cache, error) {
+	c := &cache{Entries: make(map[string]cacheEntry)}
+
+	data, err := os.ReadFile(cachePath(regionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read cache: %w", err)
+	}
+
+	err = json.Unmarshal(data, c)
+	if err != nil {
+		// A broken cache only makes generation slower.
+		return &cache{Entries: make(map[string]cacheEntry)}, nil
+	}
+
+	err = os.Remove(cachePath(regionID))
+	if err != nil {
+		return nil, fmt.Errorf("remove cache: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *cache) save(regionID string) error {
+	err := os.MkdirAll(cacheDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("make cache dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "	")
+	if err != nil {
+		return fmt.Errorf("marshal cache: %w", err)
+	}
+
+	return os.WriteFile(cachePath(regionID), data, 0o644)
+}
+
+// describe returns cache entry of asset's source file. The file is hashed only
+// if its size or modification time changed since it was cached.
+func (c *cache) describe(fsys fs.FS, asset models.Asset) (cacheEntry, error) {
+	info, err := fs.Stat(fsys, asset.Path)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+
+	entry := cacheEntry{Source: asset.Path, Size: info.Size(), ModTime: info.ModTime().UTC()}
+
+	cached, ok := c.Entries[assetKey(asset)]
+	if ok && cached.Source == entry.Source && cached.Size == entry.Size && cached.ModTime.Equal(entry.ModTime) {
+		entry.Hash = cached.Hash
+		return entry, nil
+	}
+
+	entry.Hash, err = hashFile(fsys, asset.Path)
+	return entry, err
+}
+
+// fresh returns true if asset with source described by entry was already copied
+// to outputDir.
+func (c *cache) fresh(outputDir string, asset models.Asset, entry cacheEntry) bool {
+	cached, ok := c.Entries[assetKey(asset)]
+	if !ok || cached.Source != entry.Source || cached.Hash != entry.Hash {
+		return false
+	}
+
+	_, err := os.Stat(filepath.Join(outputDir, asset.Dir, asset.Name))
+	return err == nil
+}
+
+func assetKey(asset models.Asset) string {
+	return path.Join(asset.Dir, asset.Name)
+}
+
+func hashFile(fsys fs.FS, name string) (string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("hash %s: %w", name, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// removeStale removes files from subdirectories of outputDir that don't
+// correspond to any asset.
+func removeStale(outputDir string, assets []models.Asset) error {
+	wanted := make(map[string]bool)
+	for _, asset := range assets {
+		wanted[assetKey(asset)] = true
+	}
+
+	for _, dir := range []string{models.ImagesDir, models.StoriesDir} {
+		entries, err := os.ReadDir(filepath.Join(outputDir, dir))
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if wanted[path.Join(dir, entry.Name())] {
+				continue
+			}
+
+			err = os.RemoveAll(filepath.Join(outputDir, dir, entry.Name()))
+			if err != nil {
+				return fmt.Errorf("remove stale file: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -7,11 +7,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 
+	"github.com/opentouristics/database-tools/lockfile"
 	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/parallel"
 	"github.com/opentouristics/database-tools/readers"
 )
 
@@ -19,57 +23,109 @@ func init() {
 	log.SetFlags(0)
 }
 
-// Generate walks the database and copies files from it to the generated
-// directory.
-func Generate(regionID string, quality models.Quality, verbose bool) error {
-	var datafile models.Datafile
+// Options tells how a datafile is generated.
+type Options struct {
+	Quality models.Quality
+
+	// Langs are languages which get a single-language pack, besides the pack
+	// with all languages. "all" means every language of the datafile.
+	Langs []string
+
+	// Jobs is the number of goroutines parsing places and copying files at
+	// once. It must be at least 1.
+	Jobs int
+
+	// Force makes files which haven't changed since the last generation be
+	// copied again.
+	Force bool
+
+	// Since is the previous version of the datafile (see parsePrevious). If
+	// it's not empty, places changed since then are listed in the meta.
+	Since string
+
+	// FillGaps fills texts missing in any of the datafile's languages from the
+	// fallback languages of its language policy. The previous version is
+	// filled the same way before it's compared.
+	FillGaps bool
+
+	Verbose bool
+}
 
+// Generate walks the database and copies files from it to the generated
+// directory of region regionID, as told by opts.
+//
+// Everything is written to a temporary directory, which replaces the old
+// generated directory only when generation succeeds.
+func Generate(regionID string, opts Options) error {
 	if regionID == "" {
 		return fmt.Errorf("regionID is empty")
 	}
 
-	if quality != 1 && quality != 2 {
-		return fmt.Errorf("quality is not 1 or 2")
+	if !opts.Quality.Valid() {
+		return fmt.Errorf("quality %d is not one of: %s", int(opts.Quality), models.QualityUsage())
 	}
 
-	err := os.Chdir(filepath.Join("datafiles", "datafile-"+regionID))
+	lock, err := lockfile.AcquireRegion(regionID)
 	if err != nil {
-		return fmt.Errorf("chdir into datafile's directory: %v", err)
+		return err
 	}
+	defer lock.Release()
 
-	meta, err := parseMeta()
+	datafileDir := filepath.Join("datafiles", "datafile-"+regionID)
+	datafile, err := parseDatafile(datafileDir, opts.Quality, opts.Jobs, opts.Verbose)
 	if err != nil {
-		return fmt.Errorf("failed to parse meta: %v", err)
+		return fmt.Errorf("failed to parse datafile: %v", err)
 	}
-	datafile.Meta = meta
 	datafile.Meta.GeneratedAt = readers.CurrentTime() // Important!
+	fillGeometry(&datafile)
 
-	sections, err := parseSections(verbose)
-	if err != nil {
-		return fmt.Errorf("failed to parse sections: %v", err)
+	if opts.FillGaps {
+		n := datafile.Policy().Fill(&datafile, datafile.Meta.Languages)
+		log.Printf("filled %d missing texts from fallback languages\n", n)
 	}
-	datafile.Sections = sections
-	datafile.Meta.PlaceCount = len(datafile.AllPlaces())
 
-	tracks, err := parseTracks()
+	if opts.Since != "" {
+		log.Println("comparing with the previous version...")
+		previous, version, err := parsePrevious(datafileDir, opts.Since, opts.Jobs)
+		if err != nil {
+			return fmt.Errorf("failed to parse previous version of datafile: %v", err)
+		}
+
+		// The previous version has to be filled the same way, or every
+		// fallback text would count as a change.
+		if opts.FillGaps {
+			datafile.Policy().Fill(&previous, datafile.Meta.Languages)
+		}
+
+		datafile.Meta.Changes = models.NewChangelog(version, models.Diff(&previous, &datafile))
+		log.Printf("since %s: %d new, %d updated, %d removed places\n", version,
+			len(datafile.Meta.Changes.NewPlaces), len(datafile.Meta.Changes.UpdatedPlaces), len(datafile.Meta.Changes.RemovedPlaces))
+	}
+
+	langs, err := resolveLanguages(opts.Langs, &datafile)
 	if err != nil {
-		return fmt.Errorf("failed to parse tracks: %v", err)
+		return err
 	}
-	datafile.Tracks = tracks
 
-	stories, err := parseStories()
+	assets, err := datafile.Assets()
 	if err != nil {
-		return fmt.Errorf("failed to parse stories: %v", err)
+		return fmt.Errorf("collect assets: %v", err)
 	}
-	datafile.Stories = stories
 
-	os.Chdir("../..")
+	c := &cache{Entries: make(map[string]cacheEntry)}
+	if !opts.Force {
+		c, err = loadCache(regionID)
+		if err != nil {
+			return fmt.Errorf("load cache: %v", err)
+		}
+	}
 
 	log.Println("creating output dir...")
-	outputDirPath, err := createOutputDir(regionID)
+	outputDirPath, err := createOutputDir(regionID, !opts.Force)
 	if err != nil {
 		return fmt.Errorf("failed to create output directory: %v", err)
 	}
+	defer os.RemoveAll(outputDirPath) // Does nothing after a successful commit.
 
 	log.Println("marshalling datafile to JSON...")
 	data, err := json.MarshalIndent(datafile, "", "	")
@@ -77,143 +133,138 @@ func Generate(regionID string, quality models.Quality, verbose bool) error {
 		return fmt.Errorf("failed to marshal datafile struct to JSON: %v", err)
 	}
 
-	log.Println("creating file for datafile JSON contents...")
-	dataJSONFile, err := os.Create(filepath.Join(*outputDirPath, "data.json"))
+	log.Println("validating datafile JSON against the schema...")
+	err = models.DatafileSchema().Validate(data)
 	if err != nil {
-		return fmt.Errorf("create dataJSONFile: %w", err)
+		return fmt.Errorf("datafile does not conform to the schema:\n%v", err)
 	}
 
 	log.Println("writing datafile JSON to a file...")
-	n, err := dataJSONFile.Write(data)
+	err = os.WriteFile(filepath.Join(outputDirPath, "data.json"), data, 0o644)
 	if err != nil {
 		return fmt.Errorf("failed to write data to JSON file: %v", err)
 	}
 
-	log.Printf("wrote %d KB to data.json file\n", n/1024)
+	log.Printf("wrote %d KB to data.json file\n", len(data)/1024)
 
 	log.Println("marshalling meta to JSON...")
-	data, err = json.MarshalIndent(meta, "", "	")
+	data, err = json.MarshalIndent(datafile.Meta, "", "	")
 	if err != nil {
 		return fmt.Errorf("failed to marshal datafile struct to JSON: %v", err)
 	}
 
-	log.Println("creating file for meta JSON contents...")
-	metaJSONFile, err := os.Create(filepath.Join(*outputDirPath, "meta.json"))
-	if err != nil {
-		return fmt.Errorf("create metaJSONFile: %w", err)
-	}
-
 	log.Println("writing meta JSON to file")
-	n, err = metaJSONFile.Write(data)
+	err = os.WriteFile(filepath.Join(outputDirPath, "meta.json"), data, 0o644)
 	if err != nil {
 		return fmt.Errorf("failed to write meta JSON file: %v", err)
 	}
 
-	log.Printf("wrote %d KB to meta.json file\n", n/1024)
+	log.Printf("wrote %d KB to meta.json file\n", len(data)/1024)
 
-	for _, section := range sections {
-		for _, place := range section.Places {
-			for _, imagePath := range place.ImagePaths() {
-				_, err = copyImage(regionID, imagePath)
-				if err != nil {
-					return fmt.Errorf("failed to copy image: %v", err)
-				}
-			}
-		}
+	log.Println("building search indexes...")
+	err = writeSearchIndexes(outputDirPath, &datafile, datafile.Meta.Languages)
+	if err != nil {
+		return fmt.Errorf("failed to write search indexes: %v", err)
 	}
 
-	for _, story := range stories {
-		_, err := copyMarkdown(regionID, story.MarkdownPath())
+	datafileFS := os.DirFS(datafileDir)
+
+	log.Printf("copying %d files...\n", len(assets))
+	entries := make([]cacheEntry, len(assets))
+	var skipped atomic.Int32
+	err = parallel.ForEach(len(assets), opts.Jobs, func(i int) error {
+		asset := assets[i]
+		entry, err := c.describe(datafileFS, asset)
 		if err != nil {
-			return fmt.Errorf("failed to copy markdown file for story %s: %v", story.ID, err)
+			return fmt.Errorf("failed to read file for %s: %v", asset.Owner, err)
 		}
+		entries[i] = entry
 
-		for _, path := range story.ImagePaths() {
-			_, err := copyImage(regionID, path)
-			if err != nil {
-				return fmt.Errorf("failed to copy image for story %s: %v", story.ID, err)
-			}
+		if c.fresh(outputDirPath, asset, entry) {
+			skipped.Add(1)
+			return nil
 		}
-	}
 
-	return nil
-}
+		_, err = copyFile(outputDirPath, datafileFS, asset)
+		if err != nil {
+			return fmt.Errorf("failed to copy file for %s: %v", asset.Owner, err)
+		}
 
-func copyImage(regionID string, srcPath string) (int, error) {
-	n, err := copyFile(regionID, srcPath, "images")
-	return n, err
-}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-func copyMarkdown(regionID string, srcPath string) (int, error) {
-	n, err := copyFile(regionID, srcPath, "stories")
-	return n, err
-}
+	if opts.Verbose {
+		log.Printf("skipped %d unchanged files\n", skipped.Load())
+	}
 
-func copyFile(regionID string, srcPath string, subdir string) (int, error) {
-	wd, err := os.Getwd()
+	err = removeStale(outputDirPath, assets)
 	if err != nil {
-		return 0, fmt.Errorf("get working dir: %w", err)
+		return fmt.Errorf("remove stale files: %v", err)
 	}
 
-	src, err := os.Open(srcPath)
+	err = commitOutputDir(outputDirPath, regionID)
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("failed to replace output directory: %v", err)
 	}
 
-	dstPath := filepath.Join(wd, "generated", regionID, subdir, filepath.Base(srcPath))
-	dst, err := os.Create(dstPath)
-	if err != nil {
-		return 0, fmt.Errorf("create dst file at %s: %w", dstPath, err)
+	c.Entries = make(map[string]cacheEntry, len(assets))
+	for i, asset := range assets {
+		c.Entries[assetKey(asset)] = entries[i]
 	}
 
-	n, err := io.Copy(dst, src)
+	err = c.save(regionID)
 	if err != nil {
-		return 0, fmt.Errorf("copy file from %s to %s: %w", srcPath, dstPath, err)
+		return fmt.Errorf("save cache: %v", err)
 	}
 
-	return int(n), nil
-}
-
-// CreateOutputDir creates a datafile directory structure inside generated/ in
-// project root.
-func createOutputDir(regionID string) (*string, error) {
-	generatedPath := "generated"
-	outputDirPath := filepath.Join(generatedPath, regionID)
-
-	// Check if the generated dir exists...
-	_, err := os.Stat(generatedPath)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			err = os.Mkdir(generatedPath, 0o755)
-			if err != nil {
-				return nil, fmt.Errorf("dir %#v does not exist and cannot be created: %w", generatedPath, err)
-			}
-		} else {
-			return nil, fmt.Errorf("stat %#v dir: %w", generatedPath, err)
+	for _, lang := range langs {
+		err = generateLanguagePack(regionID, lang, datafile)
+		if err != nil {
+			return fmt.Errorf("generate %s pack: %v", lang, err)
 		}
 	}
 
-	err = os.RemoveAll(outputDirPath)
+	return nil
+}
+
+// copyFile copies asset from fsys to the output directory.
+func copyFile(outputDirPath string, fsys fs.FS, asset models.Asset) (int, error) {
+	srcPath := asset.Path
+	src, err := fsys.Open(srcPath)
 	if err != nil {
-		return nil, fmt.Errorf("remove output dir %#v: %w", outputDirPath, err)
+		return 0, err
+	}
+	defer src.Close()
+
+	dstPath := filepath.Join(outputDirPath, asset.Dir, asset.Name)
+
+	// The old file is hardlinked from the previous generated directory, so it
+	// must be replaced instead of being overwritten in place.
+	err = os.Remove(dstPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("remove old dst file at %s: %w", dstPath, err)
 	}
 
-	err = os.Mkdir(outputDirPath, 0o755)
+	dst, err := os.Create(dstPath)
 	if err != nil {
-		return nil, fmt.Errorf("make output dir %#v: %w", outputDirPath, err)
+		return 0, fmt.Errorf("create dst file at %s: %w", dstPath, err)
 	}
 
-	imagesDirPath := filepath.Join(outputDirPath, "images")
-	err = os.Mkdir(imagesDirPath, 0o755)
+	n, err := io.Copy(dst, src)
 	if err != nil {
-		return nil, fmt.Errorf("make dir %#v (for images): %w", imagesDirPath, err)
+		dst.Close()
+		return 0, fmt.Errorf("copy file from %s to %s: %w", srcPath, dstPath, err)
 	}
 
-	storiesDirPath := filepath.Join(outputDirPath, "stories")
-	err = os.Mkdir(storiesDirPath, 0o755)
+	// The directory is renamed right after copying, so the file must be
+	// completely written by then.
+	err = dst.Close()
 	if err != nil {
-		return nil, fmt.Errorf("make dir %#v (for stories): %w", storiesDirPath, err)
+		return 0, fmt.Errorf("close dst file at %s: %w", dstPath, err)
 	}
 
-	return &outputDirPath, nil
+	return int(n), nil
 }
@@ -0,0 +1,98 @@
+package generate_test
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/cmd/generate"
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/sourcetest"
+)
+
+// git runs git with args in dir.
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// exampleProject makes a project directory with source of datafile of region
+// rudy (see sourcetest.Datafile), with another place called ratusz, committed
+// to git, and changes the working directory to it.
+func exampleProject(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	fsys := sourcetest.Datafile()
+	fsys["sections/01_zabytki/places/ratusz/data.json"] = sourcetest.File(`{"id": "ratusz", "section": "zabytki", "icon": "ic_ratusz", "lat": 50.3, "lng": 18.5, "images": []}`)
+	fsys["sections/01_zabytki/places/ratusz/content/pl/name.txt"] = sourcetest.File("Ratusz\n")
+	fsys["sections/01_zabytki/places/ratusz/content/en/name.txt"] = sourcetest.File("Town hall\n")
+	fsys["sections/01_zabytki/places/ratusz/content/pl/quick_info.txt"] = sourcetest.File("Ceglany\n")
+	fsys["sections/01_zabytki/places/ratusz/content/pl/overview.txt"] = sourcetest.File("Stary\n")
+	fsys["sections/01_zabytki/places/ratusz/images/compressed/ic_ratusz.webp"] = sourcetest.File("")
+
+	dir := t.TempDir()
+	datafileDir := filepath.Join(dir, "datafiles", "datafile-rudy")
+	sourcetest.Write(t, datafileDir, fsys)
+
+	git(t, datafileDir, "init", "-q")
+	git(t, datafileDir, "add", "-A")
+	git(t, datafileDir, "commit", "-q", "-m", "Initial")
+
+	t.Chdir(dir)
+	return datafileDir
+}
+
+func TestGenerateFillGapsSince(t *testing.T) {
+	datafileDir := exampleProject(t)
+
+	sourcetest.Write(t, datafileDir, fstest.MapFS{
+		"sections/01_zabytki/places/ratusz/content/pl/overview.txt": sourcetest.File("Bardzo stary\n"),
+	})
+
+	err := generate.Generate("rudy", generate.Options{Quality: models.Compressed, Jobs: 1, Since: "HEAD", FillGaps: true})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join("generated", "rudy", "data.json"))
+	if err != nil {
+		t.Fatalf("failed to read data.json: %v", err)
+	}
+
+	var datafile models.Datafile
+	err = json.Unmarshal(data, &datafile)
+	if err != nil {
+		t.Fatalf("failed to unmarshal data.json: %v", err)
+	}
+
+	for _, place := range datafile.AllPlaces() {
+		if place.ID == "kosciol" && place.QuickInfo["en"] != "Gotycki" {
+			t.Errorf("got en quick info of kosciol %q, want it filled from pl", place.QuickInfo["en"])
+		}
+	}
+
+	changes := datafile.Meta.Changes
+	if changes == nil {
+		t.Fatalf("got no changes in meta")
+	}
+
+	if want := []string{"ratusz"}; !cmp.Equal(changes.UpdatedPlaces, want) {
+		t.Errorf("got updated places %q, want %q", changes.UpdatedPlaces, want)
+	}
+
+	if len(changes.NewPlaces) != 0 || len(changes.RemovedPlaces) != 0 {
+		t.Errorf("got new places %q and removed places %q, want none", changes.NewPlaces, changes.RemovedPlaces)
+	}
+}
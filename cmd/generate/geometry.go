@@ -0,0 +1,67 @@
+package generate
+
+import (
+	"log"
+
+	"github.com/opentouristics/database-tools/models"
+)
+
+const (
+	// Hand-written center further than this from the computed one is reported.
+	centerTolerance = 100 // meters
+	// Hand-written bound further than this from every computed vertex (or the
+	// other way around) is reported.
+	boundsTolerance = 10 // meters
+)
+
+// fillGeometry computes center and bounds of the region from coordinates of its
+// places and tracks (see Datafile.Center and models.ConvexHull). Values that are missing in meta are filled in. Values that
+// were written by hand are kept, but a warning is printed when they disagree
+// with the computed ones.
+func fillGeometry(datafile *models.Datafile) {
+	locations := datafile.Locations()
+	if len(locations) == 0 {
+		log.Println("warning: datafile has no coordinates, can't compute center and bounds")
+		return
+	}
+
+	meta := &datafile.Meta
+
+	center := datafile.Center()
+	if meta.Center == (models.Location{}) {
+		meta.Center = center
+	} else if d := models.Distance(meta.Center, center); d > centerTolerance {
+		log.Printf("warning: center in meta %v is %.0f m away from the computed center %v\n", meta.Center, d, center)
+	}
+
+	bounds := models.ConvexHull(locations)
+	if len(meta.Bounds) == 0 {
+		meta.Bounds = bounds
+	} else if !sameVertices(meta.Bounds, bounds) {
+		log.Printf("warning: bounds in meta (%d vertices) differ from the computed convex hull (%d vertices)\n", len(meta.Bounds), len(bounds))
+	}
+}
+
+// sameVertices returns true if every vertex of a is close to some vertex of b
+// and vice versa. Order of vertices doesn't matter.
+func sameVertices(a, b []models.Location) bool {
+	covers := func(a, b []models.Location) bool {
+		for _, u := range a {
+			found := false
+			for _, v := range b {
+				if models.Distance(u, v) <= boundsTolerance {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return covers(a, b) && covers(b, a)
+}
@@ -0,0 +1,97 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/opentouristics/database-tools/models"
+)
+
+const generatedPath = "generated"
+
+// CreateOutputDir creates a temporary datafile directory structure inside
+// generated/ in project root. packID is ID of the region or of its
+// single-language pack. Once everything is written to it, it must be moved to
+// its final place with commitOutputDir.
+//
+// If reuse is true, files from the existing generated directory are hardlinked
+// into the new one, so that they don't have to be copied again.
+func createOutputDir(packID string, reuse bool) (string, error) {
+	// Check if the generated dir exists...
+	_, err := os.Stat(generatedPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = os.Mkdir(generatedPath, 0o755)
+			if err != nil {
+				return "", fmt.Errorf("dir %#v does not exist and cannot be created: %w", generatedPath, err)
+			}
+		} else {
+			return "", fmt.Errorf("stat %#v dir: %w", generatedPath, err)
+		}
+	}
+
+	// Leftovers of generations that crashed.
+	leftovers, _ := filepath.Glob(filepath.Join(generatedPath, "."+packID+".tmp-*"))
+	for _, leftover := range leftovers {
+		os.RemoveAll(leftover)
+	}
+
+	outputDirPath, err := os.MkdirTemp(generatedPath, "."+packID+".tmp-")
+	if err != nil {
+		return "", fmt.Errorf("make temporary output dir: %w", err)
+	}
+
+	for _, subdir := range []string{models.ImagesDir, models.StoriesDir} {
+		subdirPath := filepath.Join(outputDirPath, subdir)
+		err = os.Mkdir(subdirPath, 0o755)
+		if err != nil {
+			os.RemoveAll(outputDirPath)
+			return "", fmt.Errorf("make dir %#v (for %s): %w", subdirPath, subdir, err)
+		}
+
+		if !reuse {
+			continue
+		}
+
+		oldSubdirPath := filepath.Join(generatedPath, packID, subdir)
+		if _, err := os.Stat(oldSubdirPath); err != nil {
+			continue
+		}
+
+		err = linkDir(oldSubdirPath, subdirPath)
+		if err != nil {
+			os.RemoveAll(outputDirPath)
+			return "", fmt.Errorf("reuse files from %s: %w", oldSubdirPath, err)
+		}
+	}
+
+	return outputDirPath, nil
+}
+
+// commitOutputDir replaces the generated directory of packID with the
+// temporary one at outputDirPath.
+func commitOutputDir(outputDirPath string, packID string) error {
+	finalPath := filepath.Join(generatedPath, packID)
+	oldPath := outputDirPath + ".old"
+
+	err := os.Rename(finalPath, oldPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("move away old output dir %#v: %w", finalPath, err)
+	}
+
+	err = os.Rename(outputDirPath, finalPath)
+	if err != nil {
+		// Try to bring the old directory back.
+		os.Rename(oldPath, finalPath)
+		return fmt.Errorf("move output dir to %#v: %w", finalPath, err)
+	}
+
+	err = os.RemoveAll(oldPath)
+	if err != nil {
+		return fmt.Errorf("remove old output dir: %w", err)
+	}
+
+	return nil
+}
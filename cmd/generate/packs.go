@@ -0,0 +1,160 @@
+package generate
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/search"
+)
+
+// resolveLanguages turns languages requested by the user into the list of
+// single-language packs to generate. "all" stands for every language of the
+// datafile.
+func resolveLanguages(requested []string, datafile *models.Datafile) ([]string, error) {
+	available := datafile.Languages()
+
+	langs := make([]string, 0, len(requested))
+	for _, lang := range requested {
+		if lang == "all" {
+			return available, nil
+		}
+
+		if !slices.Contains(available, lang) {
+			return nil, fmt.Errorf("language %s is not available in the datafile (available: %v)", lang, available)
+		}
+
+		if !slices.Contains(langs, lang) {
+			langs = append(langs, lang)
+		}
+	}
+
+	return langs, nil
+}
+
+// generateLanguagePack creates a pack with texts only in lang next to the
+// region's generated directory. Images, stories and the search index in lang
+// are taken from the already generated multi-language pack.
+func generateLanguagePack(regionID string, lang string, datafile models.Datafile) error {
+	packID := models.PackID(regionID, lang)
+
+	outputDirPath, err := createOutputDir(packID, false)
+	if err != nil {
+		return fmt.Errorf("create output directory: %v", err)
+	}
+	defer os.RemoveAll(outputDirPath) // Does nothing after a successful commit.
+
+	datafile.Meta.Languages = []string{lang}
+
+	chain := datafile.Policy().Chain(lang)
+
+	data, err := models.MarshalLocalized(datafile, chain...)
+	if err != nil {
+		return fmt.Errorf("marshal datafile to JSON: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(outputDirPath, "data.json"), data, 0o644)
+	if err != nil {
+		return fmt.Errorf("write data.json: %v", err)
+	}
+
+	data, err = models.MarshalLocalized(datafile.Meta, chain...)
+	if err != nil {
+		return fmt.Errorf("marshal meta to JSON: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(outputDirPath, "meta.json"), data, 0o644)
+	if err != nil {
+		return fmt.Errorf("write meta.json: %v", err)
+	}
+
+	for _, subdir := range []string{models.ImagesDir, models.StoriesDir} {
+		err = linkDir(filepath.Join(generatedPath, regionID, subdir), filepath.Join(outputDirPath, subdir))
+		if err != nil {
+			return fmt.Errorf("link %s: %v", subdir, err)
+		}
+	}
+
+	searchDirPath := filepath.Join(outputDirPath, search.Dir)
+	err = os.Mkdir(searchDirPath, 0o755)
+	if err != nil {
+		return fmt.Errorf("make dir %#v: %v", searchDirPath, err)
+	}
+
+	indexPath := filepath.Join(generatedPath, regionID, search.Dir, search.Filename(lang))
+	err = os.Link(indexPath, filepath.Join(searchDirPath, search.Filename(lang)))
+	if err != nil {
+		err = copyLocalFile(indexPath, filepath.Join(searchDirPath, search.Filename(lang)))
+		if err != nil {
+			return fmt.Errorf("link search index: %v", err)
+		}
+	}
+
+	err = commitOutputDir(outputDirPath, packID)
+	if err != nil {
+		return fmt.Errorf("replace output directory: %v", err)
+	}
+
+	log.Printf("generated %s pack %s\n", lang, packID)
+	return nil
+}
+
+// linkDir hardlinks all files from srcDir into dstDir. Files that can't be
+// linked (e.g because they are on a different device) are copied.
+func linkDir(srcDir string, dstDir string) error {
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		srcPath := filepath.Join(srcDir, entry.Name())
+		dstPath := filepath.Join(dstDir, entry.Name())
+
+		err = os.Link(srcPath, dstPath)
+		if err == nil {
+			continue
+		}
+
+		err = copyLocalFile(srcPath, dstPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyLocalFile(srcPath string, dstPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("create dst file at %s: %w", dstPath, err)
+	}
+
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		return fmt.Errorf("copy file from %s to %s: %w", srcPath, dstPath, err)
+	}
+
+	err = dst.Close()
+	if err != nil {
+		return fmt.Errorf("close dst file at %s: %w", dstPath, err)
+	}
+
+	return nil
+}
@@ -4,14 +4,14 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"github.com/opentouristics/database-tools/models"
 )
 
-func getCommitHash() (string, error) {
+func getCommitHash(dir string) (string, error) {
 	cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
+	cmd.Dir = dir
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("git rev-parse: %v", err)
@@ -21,8 +21,9 @@ func getCommitHash() (string, error) {
 	return hash, nil
 }
 
-func getCommitTag() (string, error) {
+func getCommitTag(dir string) (string, error) {
 	cmd := exec.Command("git", "tag", "--points-at")
+	cmd.Dir = dir
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("git tag: %v", err)
@@ -32,135 +33,69 @@ func getCommitTag() (string, error) {
 	return tag, nil
 }
 
-func parseMeta() (meta models.Meta, err error) {
-	os.Chdir("meta")
+// parseDatafile parses the datafile source at datafileDir, describes its images
+// and fills in the metadata that depends on the datafile's git repository.
+func parseDatafile(datafileDir string, quality models.Quality, jobs int, verbose bool) (datafile models.Datafile, err error) {
+	datafile, err = models.ParseDatafile(os.DirFS(datafileDir), quality, jobs, verbose)
+	if err != nil {
+		return
+	}
 
-	err = meta.Parse()
+	err = models.ReferencesError(datafile.CheckReferences())
 	if err != nil {
-		err = fmt.Errorf("parse meta: %w", err)
+		err = fmt.Errorf("broken references:\n%w", err)
 		return
 	}
 
-	os.Chdir("..")
+	err = datafile.DescribeImages(os.DirFS(datafileDir), jobs)
+	if err != nil {
+		err = fmt.Errorf("describe images: %v", err)
+		return
+	}
 
-	commitHash, err := getCommitHash()
+	commitHash, err := getCommitHash(datafileDir)
 	if err != nil {
 		err = fmt.Errorf("get commit hash: %v", err)
 		return
 	}
-	meta.CommitHash = commitHash
+	datafile.Meta.CommitHash = commitHash
 
-	commitTag, err := getCommitTag()
+	commitTag, err := getCommitTag(datafileDir)
 	if err != nil {
 		err = fmt.Errorf("get commit tag: %v", err)
 		return
 	}
 	if commitTag == "" {
-		meta.CommitTag = nil
+		datafile.Meta.CommitTag = nil
 	} else {
-		meta.CommitTag = &commitTag
+		datafile.Meta.CommitTag = &commitTag
 	}
 
 	return
 }
 
-func parseSections(verbose bool) ([]models.Section, error) {
-	sections := make([]models.Section, 0)
-
-	walker := func(path string, _ os.FileInfo, err error) error {
-		if err != nil {
-			return fmt.Errorf("start walk section %s: %w", path, err)
-		}
-
-		level := strings.Count(path, "/")
-		if level != 1 {
-			return nil
-		}
-		// Jump 2 levels down, to the sections's directory.
-		os.Chdir(path)
-
-		var section models.Section
-		err = section.Parse(verbose)
-		if err != nil {
-			return fmt.Errorf("parse %s: %w", path, err)
-		}
-		os.Chdir("../..")
-
-		sections = append(sections, section)
-		return nil
-	}
-	err := filepath.Walk("sections", walker)
-	if err != nil {
-		return sections, fmt.Errorf("walk sections: %w", err)
-	}
-
-	return sections, err
-}
-
-func parseTracks() ([]models.Track, error) {
-	tracks := make([]models.Track, 0)
-
-	if _, err := os.Stat("tracks"); os.IsNotExist(err) {
-		return tracks, nil
+// parsePrevious loads the previous version of the datafile, which is either a
+// generated directory or a compressed datafile at path since, or the datafile
+// source at datafileDir at git revision since. It also returns the version of
+// the previous datafile.
+func parsePrevious(datafileDir string, since string, jobs int) (models.Datafile, string, error) {
+	if _, err := os.Stat(since); err != nil {
+		previous, err := models.Load(datafileDir+"@"+since, jobs)
+		return previous, since, err
 	}
 
-	walker := func(path string, _ os.FileInfo, err error) error {
-		if err != nil {
-			return fmt.Errorf("parse track %s: %w", path, err)
-		}
-
-		level := strings.Count(path, "/")
-		if level != 1 {
-			return nil
-		}
-		// Jump 2 levels down, to the track's directory.
-		os.Chdir(path)
-
-		var track models.Track
-		err = track.Parse()
-
-		tracks = append(tracks, track)
-		os.Chdir("../..")
-
-		return err
+	previous, err := models.Load(since, jobs)
+	if err != nil {
+		return previous, "", err
 	}
-	err := filepath.Walk("tracks", walker)
-
-	return tracks, err
-}
-
-func parseStories() ([]models.Story, error) {
-	stories := make([]models.Story, 0)
 
-	if _, err := os.Stat("stories"); os.IsNotExist(err) {
-		return stories, nil
+	version := previous.Meta.CommitHash
+	if previous.Meta.CommitTag != nil && *previous.Meta.CommitTag != "" {
+		version = *previous.Meta.CommitTag
 	}
-
-	walker := func(path string, _ os.FileInfo, err error) error {
-		if err != nil {
-			return fmt.Errorf("parse story %s: %w", path, err)
-		}
-
-		level := strings.Count(path, "/")
-		if level != 1 {
-			return nil
-		}
-		// Jump 2 levels down, to the story's directory.
-		os.Chdir(path)
-
-		var story models.Story
-		err = story.Parse()
-		if err != nil {
-			err = fmt.Errorf("parse story %s: %w", path, err)
-		}
-
-		stories = append(stories, story)
-		os.Chdir("../..")
-
-		return err
+	if version == "" {
+		version = since
 	}
 
-	err := filepath.Walk("stories", walker)
-
-	return stories, err
+	return previous, version, nil
 }
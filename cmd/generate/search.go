@@ -0,0 +1,37 @@
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/search"
+)
+
+// writeSearchIndexes writes the search index of datafile in every language of
+// langs to the search directory in outputDirPath.
+func writeSearchIndexes(outputDirPath string, datafile *models.Datafile, langs []string) error {
+	searchDirPath := filepath.Join(outputDirPath, search.Dir)
+	err := os.MkdirAll(searchDirPath, 0o755)
+	if err != nil {
+		return fmt.Errorf("make dir %#v: %w", searchDirPath, err)
+	}
+
+	for _, lang := range langs {
+		index := search.Build(datafile, datafile.Policy().Chain(lang)...)
+
+		data, err := json.Marshal(index)
+		if err != nil {
+			return fmt.Errorf("marshal %s index: %w", lang, err)
+		}
+
+		err = os.WriteFile(filepath.Join(searchDirPath, search.Filename(lang)), data, 0o644)
+		if err != nil {
+			return fmt.Errorf("write %s index: %w", lang, err)
+		}
+	}
+
+	return nil
+}
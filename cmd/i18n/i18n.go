@@ -0,0 +1,265 @@
+// Package i18n implements exchanging texts of a datafile with translators as
+// gettext PO files.
+package i18n
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/opentouristics/database-tools/models"
+)
+
+// translatedComment marks entries which were already translated when they were
+// exported.
+const translatedComment = "translated"
+
+var headerKeys = []string{"Project-Id-Version", "MIME-Version", "Content-Type", "Content-Transfer-Encoding", "Language", "X-Source-Language"}
+
+// Export writes texts of region's datafile in language from, together with
+// their translations to language to, as a PO file to output (stdout if empty).
+//
+// Every text file (see models.Measure) is a single entry. Its msgctxt is the
+// path to the file with the content/<lang> part removed, e.g
+// sections/01_zabytki/places/kosciol/name.txt. Entries which are already
+// translated have their msgstr filled in.
+func Export(regionID string, from string, to string, output string) error {
+	datafileDir := filepath.Join("datafiles", "datafile-"+regionID)
+	if _, err := os.Stat(datafileDir); err != nil {
+		return fmt.Errorf("stat datafile's directory: %w", err)
+	}
+
+	f, err := export(os.DirFS(datafileDir), from, to)
+	if err != nil {
+		return err
+	}
+	f.header["Project-Id-Version"] = "datafile-" + regionID
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("create output file: %v", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	err = writePO(w, f, headerKeys)
+	if err != nil {
+		return fmt.Errorf("write PO file: %v", err)
+	}
+
+	translated := 0
+	for _, e := range f.entries {
+		if e.str != "" {
+			translated++
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d texts, %d already translated to %s\n", len(f.entries), translated, to)
+
+	return nil
+}
+
+func export(fsys fs.FS, from string, to string) (poFile, error) {
+	f := poFile{header: map[string]string{
+		"MIME-Version":              "1.0",
+		"Content-Type":              "text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding": "8bit",
+		"Language":                  to,
+		"X-Source-Language":         from,
+	}}
+
+	if from == "" || to == "" || from == to {
+		return f, fmt.Errorf("source and target languages must be different and not empty")
+	}
+
+	c, err := models.Measure(fsys)
+	if err != nil {
+		return f, fmt.Errorf("find texts: %v", err)
+	}
+
+	for _, cell := range c.Cells {
+		if cell.Lang != from || !cell.Present {
+			continue
+		}
+
+		key := textKey(cell.Path)
+
+		source, err := readText(fsys, textPath(key, from))
+		if err != nil {
+			return f, err
+		}
+
+		translation, err := readText(fsys, textPath(key, to))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return f, err
+		}
+
+		e := entry{
+			comments:  []string{cell.Entity + " " + cell.EntityID},
+			reference: cell.Path,
+			context:   key,
+			id:        source,
+			str:       translation,
+		}
+		if translation != "" {
+			e.comments = append(e.comments, translatedComment)
+		}
+
+		f.entries = append(f.entries, e)
+	}
+
+	return f, nil
+}
+
+// Import reads translations from PO file input and writes them to the
+// content/<lang> directories of region's datafile, where lang is the
+// file's Language.
+//
+// Entries whose keys don't exist anymore are reported and skipped. So are
+// entries whose source text has changed since the export, unless allowStale
+// is true. Fuzzy and untranslated entries are skipped.
+func Import(regionID string, input string, allowStale bool) error {
+	datafileDir := filepath.Join("datafiles", "datafile-"+regionID)
+	if _, err := os.Stat(datafileDir); err != nil {
+		return fmt.Errorf("stat datafile's directory: %w", err)
+	}
+
+	file, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("open input file: %v", err)
+	}
+	defer file.Close()
+
+	f, err := readPO(file)
+	if err != nil {
+		return fmt.Errorf("read PO file %s: %v", input, err)
+	}
+
+	result, err := plan(os.DirFS(datafileDir), f, allowStale)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range result.unknown {
+		fmt.Printf("unknown: %s does not exist anymore\n", key)
+	}
+	for _, key := range result.stale {
+		fmt.Printf("stale: source text of %s has changed since the export\n", key)
+	}
+
+	for _, w := range result.writes {
+		filePath := filepath.Join(datafileDir, filepath.FromSlash(w.path))
+		err = os.MkdirAll(filepath.Dir(filePath), 0o755)
+		if err != nil {
+			return fmt.Errorf("make directory for %s: %v", w.path, err)
+		}
+
+		err = os.WriteFile(filePath, []byte(w.content), 0o644)
+		if err != nil {
+			return fmt.Errorf("write %s: %v", w.path, err)
+		}
+	}
+
+	fmt.Printf("imported %d translations, %d unchanged, %d unknown, %d stale\n", len(result.writes), result.unchanged, len(result.unknown), len(result.stale))
+
+	if len(result.unknown) > 0 {
+		return fmt.Errorf("found %d unknown keys", len(result.unknown))
+	}
+
+	return nil
+}
+
+// write is a text file to be written by Import.
+type write struct {
+	path    string
+	content string
+}
+
+type importResult struct {
+	writes    []write
+	unchanged int
+	unknown   []string // Keys of texts which don't exist.
+	stale     []string // Keys of texts whose source changed since the export.
+}
+
+// plan decides which files of the datafile in fsys have to be written to
+// import translations from f.
+func plan(fsys fs.FS, f poFile, allowStale bool) (importResult, error) {
+	var result importResult
+
+	from, to := f.header["X-Source-Language"], f.header["Language"]
+	if from == "" || to == "" {
+		return result, fmt.Errorf("PO file has no Language or X-Source-Language header")
+	}
+
+	for _, e := range f.entries {
+		if e.fuzzy || e.str == "" {
+			continue
+		}
+
+		source, err := readText(fsys, textPath(e.context, from))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				result.unknown = append(result.unknown, e.context)
+				continue
+			}
+			return result, err
+		}
+
+		if source != e.id {
+			result.stale = append(result.stale, e.context)
+			if !allowStale {
+				continue
+			}
+		}
+
+		translation, err := readText(fsys, textPath(e.context, to))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, err
+		}
+
+		if translation == e.str {
+			result.unchanged++
+			continue
+		}
+
+		result.writes = append(result.writes, write{path: textPath(e.context, to), content: e.str + "\n"})
+	}
+
+	return result, nil
+}
+
+// textKey returns key of text file at filePath, which is filePath without its
+// content/<lang> part.
+func textKey(filePath string) string {
+	dir, filename := path.Split(filePath)
+	entityDir := path.Dir(path.Dir(path.Clean(dir)))
+	return path.Join(entityDir, filename)
+}
+
+// textPath returns path to the text file with key in lang.
+func textPath(key string, lang string) string {
+	dir, filename := path.Split(key)
+	return path.Join(dir, "content", lang, filename)
+}
+
+// readText returns contents of text file at filePath, without the final
+// newline.
+func readText(fsys fs.FS, filePath string) (string, error) {
+	data, err := fs.ReadFile(fsys, filePath)
+	if err != nil {
+		return "", err
+	}
+
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
@@ -0,0 +1,73 @@
+package i18n
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/sourcetest"
+)
+
+func TestExportImport(t *testing.T) {
+	fsys := sourcetest.Datafile()
+	fsys["sections/01_zabytki/content/pl/quick_info.txt"] = sourcetest.File("Stare \"budynki\"\n")
+	fsys["sections/01_zabytki/places/kosciol/content/pl/text_1.txt"] = sourcetest.File("# Historia\nZbudowany\tw XV wieku.\n")
+
+	exported, err := export(fsys, "pl", "en")
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = writePO(&buf, exported, headerKeys)
+	if err != nil {
+		t.Fatalf("failed to write PO: %v", err)
+	}
+
+	f, err := readPO(&buf)
+	if err != nil {
+		t.Fatalf("failed to read PO: %v\n%s", err, buf.String())
+	}
+
+	if diff := cmp.Diff(exported, f, cmp.AllowUnexported(poFile{}, entry{})); diff != "" {
+		t.Fatalf("PO file changed after round trip (-want +got):\n%s", diff)
+	}
+
+	if got, want := f.entries[0].comments, []string{"meta meta", translatedComment}; !cmp.Equal(got, want) {
+		t.Errorf("got comments %q of translated entry, want %q", got, want)
+	}
+
+	translations := map[string]string{
+		"sections/01_zabytki/name.txt":                  "Monuments",
+		"sections/01_zabytki/quick_info.txt":            "Old \"buildings\"",
+		"sections/01_zabytki/places/kosciol/text_1.txt": "# History\nBuilt in the 15th century.",
+	}
+	for i := range f.entries {
+		if translation, ok := translations[f.entries[i].context]; ok {
+			f.entries[i].str = translation
+		}
+	}
+
+	// The source changed after the export.
+	fsys["sections/01_zabytki/content/pl/name.txt"] = sourcetest.File("Zabytki i pomniki\n")
+	f.entries = append(f.entries, entry{context: "sections/01_zabytki/places/palac/name.txt", id: "Pałac", str: "Palace"})
+
+	result, err := plan(fsys, f, false)
+	if err != nil {
+		t.Fatalf("failed to plan import: %v", err)
+	}
+
+	want := importResult{
+		writes: []write{
+			{path: "sections/01_zabytki/content/en/quick_info.txt", content: "Old \"buildings\"\n"},
+			{path: "sections/01_zabytki/places/kosciol/content/en/text_1.txt", content: "# History\nBuilt in the 15th century.\n"},
+		},
+		unchanged: 3, // Names of meta, kosciol and legenda.
+		unknown:   []string{"sections/01_zabytki/places/palac/name.txt"},
+		stale:     []string{"sections/01_zabytki/name.txt"},
+	}
+
+	if diff := cmp.Diff(want, result, cmp.AllowUnexported(importResult{}, write{})); diff != "" {
+		t.Errorf("import result mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,184 @@
+package i18n
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// entry is a single message of a gettext PO file.
+type entry struct {
+	comments  []string // Extracted comments (#.).
+	reference string   // Reference to the source file (#:).
+	fuzzy     bool
+	context   string // msgctxt
+	id        string // msgid, the source text.
+	str       string // msgstr, the translated text.
+}
+
+// poFile is a gettext PO file. Its header is kept separately from entries.
+type poFile struct {
+	header  map[string]string
+	entries []entry
+}
+
+// writePO writes file f to w. Header fields are written in the order of
+// headerKeys.
+func writePO(w io.Writer, f poFile, headerKeys []string) error {
+	bw := bufio.NewWriter(w)
+
+	var header strings.Builder
+	for _, key := range headerKeys {
+		if value, ok := f.header[key]; ok {
+			header.WriteString(key + ": " + value + "\n")
+		}
+	}
+
+	fmt.Fprintln(bw, `msgid ""`)
+	writePOString(bw, "msgstr", header.String())
+
+	for _, e := range f.entries {
+		fmt.Fprintln(bw)
+		for _, comment := range e.comments {
+			fmt.Fprintln(bw, "#. "+comment)
+		}
+		if e.reference != "" {
+			fmt.Fprintln(bw, "#: "+e.reference)
+		}
+		if e.fuzzy {
+			fmt.Fprintln(bw, "#, fuzzy")
+		}
+		writePOString(bw, "msgctxt", e.context)
+		writePOString(bw, "msgid", e.id)
+		writePOString(bw, "msgstr", e.str)
+	}
+
+	return bw.Flush()
+}
+
+// writePOString writes keyword followed by quoted s. Multi-line strings are
+// split into one quoted string per line.
+func writePOString(w io.Writer, keyword string, s string) {
+	if !strings.Contains(strings.TrimSuffix(s, "\n"), "\n") {
+		fmt.Fprintf(w, "%s %s\n", keyword, quotePO(s))
+		return
+	}
+
+	fmt.Fprintf(w, "%s \"\"\n", keyword)
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			fmt.Fprintln(w, quotePO(line))
+		}
+	}
+}
+
+func quotePO(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// readPO parses a PO file from r.
+func readPO(r io.Reader) (poFile, error) {
+	f := poFile{header: make(map[string]string)}
+
+	var (
+		current entry
+		field   *string // Field to which continuation strings are appended.
+		started bool    // Whether current has any keyword yet.
+	)
+
+	flush := func() {
+		if !started {
+			return
+		}
+
+		if current.id == "" && current.context == "" {
+			for _, line := range strings.Split(current.str, "\n") {
+				key, value, ok := strings.Cut(line, ":")
+				if ok {
+					f.header[strings.TrimSpace(key)] = strings.TrimSpace(value)
+				}
+			}
+		} else {
+			f.entries = append(f.entries, current)
+		}
+
+		current = entry{}
+		field = nil
+		started = false
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "#"):
+			if started {
+				flush()
+			}
+
+			switch {
+			case strings.HasPrefix(line, "#."):
+				current.comments = append(current.comments, strings.TrimSpace(line[2:]))
+			case strings.HasPrefix(line, "#:"):
+				current.reference = strings.TrimSpace(line[2:])
+			case strings.HasPrefix(line, "#,"):
+				current.fuzzy = current.fuzzy || strings.Contains(line, "fuzzy")
+			}
+		case strings.HasPrefix(line, `"`):
+			if field == nil {
+				return f, fmt.Errorf("line %d: string without a keyword", lineNumber)
+			}
+
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				return f, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			*field += s
+		default:
+			keyword, value, _ := strings.Cut(line, " ")
+
+			// Entries may be not separated by empty lines, so msgctxt or msgid
+			// after msgstr starts a new entry.
+			switch keyword {
+			case "msgctxt":
+				if field == &current.str || current.id != "" {
+					flush()
+				}
+				field = &current.context
+			case "msgid":
+				if field == &current.str {
+					flush()
+				}
+				field = &current.id
+			case "msgstr":
+				field = &current.str
+			default:
+				return f, fmt.Errorf("line %d: unknown keyword %#v", lineNumber, keyword)
+			}
+
+			s, err := strconv.Unquote(strings.TrimSpace(value))
+			if err != nil {
+				return f, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			*field = s
+			started = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return f, err
+	}
+
+	flush()
+
+	return f, nil
+}
@@ -0,0 +1,254 @@
+// Package locate implements suggesting coordinates of places from GPS
+// positions of their photos.
+package locate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opentouristics/database-tools/exif"
+	"github.com/opentouristics/database-tools/models"
+)
+
+// photoExts are extensions of original photos which can carry GPS position.
+var photoExts = []string{".jpg", ".jpeg", ".heic"}
+
+// Suggestion compares coordinates of a place with the positions of its photos.
+type Suggestion struct {
+	PlaceID   string          `json:"place_id"`
+	Dir       string          `json:"dir"` // Directory of the place, relative to the datafile's root.
+	Current   models.Location `json:"current"`
+	Suggested models.Location `json:"suggested"` // Median position of the photos.
+	Photos    int             `json:"photos"`    // Number of photos with GPS position.
+	Distance  float64         `json:"distance"`  // Between current and suggested coordinates, in meters.
+}
+
+// Check compares coordinates of places of region's datafile with positions of
+// their photos, and prints places which are more than threshold meters off. If
+// placeID isn't empty, only that place is checked. If write is true, suggested
+// coordinates of those places are written to their data.json files.
+func Check(regionID string, placeID string, threshold float64, write bool) error {
+	datafileDir := filepath.Join("datafiles", "datafile-"+regionID)
+	if _, err := os.Stat(datafileDir); err != nil {
+		return fmt.Errorf("stat datafile's directory: %w", err)
+	}
+
+	suggestions, err := Suggest(datafileDir)
+	if err != nil {
+		return err
+	}
+
+	if placeID != "" {
+		i := slices.IndexFunc(suggestions, func(s Suggestion) bool { return s.PlaceID == placeID })
+		if i == -1 {
+			return fmt.Errorf("place %s doesn't exist or has no photos with GPS position", placeID)
+		}
+		suggestions = suggestions[i : i+1]
+	}
+
+	mismatched := Mismatched(suggestions, threshold)
+	WriteText(os.Stdout, mismatched)
+	log.Printf("%d of %d places with geotagged photos are more than %.0f m off\n", len(mismatched), len(suggestions), threshold)
+
+	if !write {
+		return nil
+	}
+
+	for _, s := range mismatched {
+		err = Write(datafileDir, s)
+		if err != nil {
+			return fmt.Errorf("place %s: %w", s.PlaceID, err)
+		}
+	}
+	log.Printf("wrote suggested coordinates of %d places\n", len(mismatched))
+
+	return nil
+}
+
+// Suggest returns suggestions for all places of the datafile at datafileDir
+// which have at least one original photo with GPS position.
+func Suggest(datafileDir string) ([]Suggestion, error) {
+	placeDirs, err := filepath.Glob(filepath.Join(datafileDir, "sections", "*", "places", "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]Suggestion, 0)
+	for _, placeDir := range placeDirs {
+		data, err := os.ReadFile(filepath.Join(placeDir, "data.json"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var place models.Place
+		err = json.Unmarshal(data, &place)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal %s: %w", placeDir, err)
+		}
+
+		positions, err := photoPositions(filepath.Join(placeDir, "images", "original"))
+		if err != nil {
+			return nil, err
+		}
+		if len(positions) == 0 {
+			continue
+		}
+
+		dir, err := filepath.Rel(datafileDir, placeDir)
+		if err != nil {
+			return nil, err
+		}
+
+		s := Suggestion{
+			PlaceID:   place.ID,
+			Dir:       filepath.ToSlash(dir),
+			Current:   models.Location{Lat: place.Lat, Lng: place.Lng},
+			Suggested: Median(positions),
+			Photos:    len(positions),
+		}
+		s.Distance = models.Distance(s.Current, s.Suggested)
+
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions, nil
+}
+
+// photoPositions returns GPS positions of photos in dir. Photos without
+// position or with broken metadata are skipped.
+func photoPositions(dir string) ([]exif.Position, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	positions := make([]exif.Position, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains(photoExts, strings.ToLower(filepath.Ext(entry.Name()))) {
+			continue
+		}
+
+		metadata, err := exif.Read(filepath.Join(dir, entry.Name()))
+		if err == nil && metadata.GPS != nil {
+			positions = append(positions, *metadata.GPS)
+		}
+	}
+
+	return positions, nil
+}
+
+// Median returns the component-wise median of positions, rounded to 5 decimal
+// places. Unlike the mean, it isn't pulled away by a single photo taken
+// somewhere else.
+func Median(positions []exif.Position) models.Location {
+	lats := make([]float64, 0, len(positions))
+	lngs := make([]float64, 0, len(positions))
+	for _, position := range positions {
+		lats = append(lats, position.Lat)
+		lngs = append(lngs, position.Lng)
+	}
+
+	return models.Location{Lat: round5(median(lats)), Lng: round5(median(lngs))}
+}
+
+func median(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+
+	sort.Float64s(xs)
+	if len(xs)%2 == 1 {
+		return xs[len(xs)/2]
+	}
+
+	return (xs[len(xs)/2-1] + xs[len(xs)/2]) / 2
+}
+
+func round5(x float64) float32 {
+	return float32(math.Round(x*1e5) / 1e5)
+}
+
+// Mismatched returns suggestions whose distance is greater than threshold
+// meters, farthest first.
+func Mismatched(suggestions []Suggestion, threshold float64) []Suggestion {
+	mismatched := make([]Suggestion, 0)
+	for _, s := range suggestions {
+		if s.Distance > threshold {
+			mismatched = append(mismatched, s)
+		}
+	}
+
+	sort.SliceStable(mismatched, func(i, j int) bool {
+		return mismatched[i].Distance > mismatched[j].Distance
+	})
+
+	return mismatched
+}
+
+// WriteText writes suggestions to w, one per line.
+func WriteText(w io.Writer, suggestions []Suggestion) {
+	for _, s := range suggestions {
+		fmt.Fprintf(w, "%s (%s): %s -> %s, %s off (%d photos)\n", s.PlaceID, s.Dir, formatLocation(s.Current), formatLocation(s.Suggested), formatDistance(s.Distance), s.Photos)
+	}
+}
+
+func formatLocation(l models.Location) string {
+	return fmt.Sprintf("%.5f, %.5f", l.Lat, l.Lng)
+}
+
+func formatDistance(meters float64) string {
+	if meters >= 1000 {
+		return fmt.Sprintf("%.1f km", meters/1000)
+	}
+
+	return fmt.Sprintf("%.0f m", meters)
+}
+
+var (
+	latRegexp = regexp.MustCompile(`("lat"\s*:\s*)-?[0-9][0-9.eE+-]*`)
+	lngRegexp = regexp.MustCompile(`("lng"\s*:\s*)-?[0-9][0-9.eE+-]*`)
+)
+
+// Write replaces coordinates in data.json of the place of suggestion s with
+// the suggested ones. The rest of the file is kept as it is.
+func Write(datafileDir string, s Suggestion) error {
+	dataPath := filepath.Join(datafileDir, filepath.FromSlash(s.Dir), "data.json")
+	data, err := os.ReadFile(dataPath)
+	if err != nil {
+		return err
+	}
+
+	if len(latRegexp.FindAll(data, -1)) != 1 || len(lngRegexp.FindAll(data, -1)) != 1 {
+		return fmt.Errorf("%s must have exactly one lat and lng field", dataPath)
+	}
+
+	data = latRegexp.ReplaceAll(data, []byte("${1}"+formatCoordinate(s.Suggested.Lat)))
+	data = lngRegexp.ReplaceAll(data, []byte("${1}"+formatCoordinate(s.Suggested.Lng)))
+
+	var place models.Place
+	err = json.Unmarshal(data, &place)
+	if err != nil {
+		return fmt.Errorf("unmarshal updated %s: %w", dataPath, err)
+	}
+
+	return os.WriteFile(dataPath, data, 0o644)
+}
+
+func formatCoordinate(x float32) string {
+	return strconv.FormatFloat(float64(x), 'f', -1, 32)
+}
@@ -0,0 +1,59 @@
+package locate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentouristics/database-tools/cmd/locate"
+	"github.com/opentouristics/database-tools/exif"
+	"github.com/opentouristics/database-tools/models"
+)
+
+func TestMedian(t *testing.T) {
+	positions := []exif.Position{
+		{Lat: 50.19001, Lng: 18.44002},
+		{Lat: 50.19003, Lng: 18.44001},
+		{Lat: 52.22977, Lng: 21.01178}, // Taken far away.
+		{Lat: 50.19002, Lng: 18.44003},
+		{Lat: 50.19004, Lng: 18.44004},
+	}
+
+	got := locate.Median(positions)
+	want := models.Location{Lat: 50.19003, Lng: 18.44003}
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	placeDir := filepath.Join(dir, "sections", "01_z", "places", "kosciol")
+	os.MkdirAll(placeDir, 0o755)
+	os.WriteFile(filepath.Join(placeDir, "data.json"), []byte(`{
+  "id": "kosciol",
+  "lat": 50.1,
+  "lng": 18,
+  "images": ["k1"]
+}`), 0o644)
+
+	err := locate.Write(dir, locate.Suggestion{
+		PlaceID:   "kosciol",
+		Dir:       "sections/01_z/places/kosciol",
+		Suggested: models.Location{Lat: 50.19003, Lng: -18.44},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(filepath.Join(placeDir, "data.json"))
+	want := `{
+  "id": "kosciol",
+  "lat": 50.19003,
+  "lng": -18.44,
+  "images": ["k1"]
+}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
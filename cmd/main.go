@@ -5,11 +5,20 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 
+	"github.com/opentouristics/database-tools/cmd/audit"
 	"github.com/opentouristics/database-tools/cmd/compress"
+	"github.com/opentouristics/database-tools/cmd/coverage"
+	"github.com/opentouristics/database-tools/cmd/diff"
 	"github.com/opentouristics/database-tools/cmd/generate"
+	"github.com/opentouristics/database-tools/cmd/i18n"
+	"github.com/opentouristics/database-tools/cmd/locate"
 	"github.com/opentouristics/database-tools/cmd/optimize"
+	"github.com/opentouristics/database-tools/cmd/schema"
 	"github.com/opentouristics/database-tools/cmd/upload"
+	"github.com/opentouristics/database-tools/cmd/validate"
 	"github.com/opentouristics/database-tools/models"
 	"github.com/urfave/cli/v2"
 )
@@ -30,8 +39,30 @@ var generateCommand = cli.Command{
 		&cli.IntFlag{
 			Name:    "quality",
 			Aliases: []string{"q"},
-			Value:   models.Compressed,
-			Usage:   "quality of photos in the datafile (1 - compressed, 2 - original)",
+			Value:   int(models.Compressed),
+			Usage:   "quality of photos in the datafile (" + models.QualityUsage() + ")",
+		},
+		&cli.StringSliceFlag{
+			Name:  "lang",
+			Usage: "additionally generate a single-language pack in this language (\"all\" for every language)",
+		},
+		&cli.IntFlag{
+			Name:    "jobs",
+			Aliases: []string{"j"},
+			Value:   runtime.NumCPU(),
+			Usage:   "number of places and files processed at the same time",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "rebuild the generated directory from scratch, even if files didn't change",
+		},
+		&cli.BoolFlag{
+			Name:  "fill-gaps",
+			Usage: "fill texts missing in any language from the fallback languages of the region's language policy",
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "list places changed since this version (generated directory, .zip file or git revision of the source) in meta",
 		},
 		&cli.BoolFlag{
 			Name:    "verbose",
@@ -42,18 +73,388 @@ var generateCommand = cli.Command{
 	Action: func(c *cli.Context) error {
 		regionID := c.String("region-id")
 
-		quality := models.Quality(c.Int("quality"))
-		verbose := c.Bool("verbose")
+		opts := generate.Options{
+			Quality:  models.Quality(c.Int("quality")),
+			Langs:    c.StringSlice("lang"),
+			Jobs:     c.Int("jobs"),
+			Force:    c.Bool("force"),
+			Since:    c.String("since"),
+			FillGaps: c.Bool("fill-gaps"),
+			Verbose:  c.Bool("verbose"),
+		}
 
 		if regionID == "" {
 			return fmt.Errorf("region id is empty")
 		}
 
-		err := generate.Generate(regionID, quality, verbose)
+		if opts.Jobs < 1 {
+			return fmt.Errorf("jobs must be at least 1")
+		}
+
+		err := generate.Generate(regionID, opts)
 		return err
 	},
 }
 
+var validateCommand = cli.Command{
+	Name:  "validate",
+	Usage: "report all problems in region's datafile source",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "region-id",
+			Aliases: []string{"id"},
+			Usage:   "region whose datafile source will be validated",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "format of the report (text or json)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		regionID := c.String("region-id")
+		format := c.String("format")
+
+		if regionID == "" {
+			return fmt.Errorf("region id is empty")
+		}
+
+		err := validate.Validate(regionID, format)
+		return err
+	},
+}
+
+var coverageCommand = cli.Command{
+	Name:  "coverage",
+	Usage: "report how completely texts of region's datafile source are translated",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "region-id",
+			Aliases: []string{"id"},
+			Usage:   "region whose datafile source will be measured",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "format of the report (text or json)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "require",
+			Usage: "fail if coverage of this language is below the threshold",
+		},
+		&cli.Float64Flag{
+			Name:  "threshold",
+			Value: 100,
+			Usage: "minimum coverage of required languages, in percent",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		regionID := c.String("region-id")
+		format := c.String("format")
+		required := c.StringSlice("require")
+		threshold := c.Float64("threshold")
+
+		if regionID == "" {
+			return fmt.Errorf("region id is empty")
+		}
+
+		err := coverage.Check(regionID, format, required, threshold)
+		return err
+	},
+}
+
+var auditCommand = cli.Command{
+	Name:  "audit",
+	Usage: "audit files of region's datafile source",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "images",
+			Usage: "report sizes, dimensions and aspect ratios of images and fail if they exceed budgets",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "region-id",
+					Aliases: []string{"id"},
+					Usage:   "region whose images will be audited",
+				},
+				&cli.StringFlag{
+					Name:  "kind",
+					Value: "all",
+					Usage: "audit only images, only icons or all",
+				},
+				&cli.StringFlag{
+					Name:  "tier",
+					Value: "all",
+					Usage: "audit only original, only compressed or all images",
+				},
+				&cli.StringFlag{
+					Name:  "format",
+					Value: "text",
+					Usage: "format of the report (text, csv or json)",
+				},
+				&cli.StringFlag{
+					Name:  "sort-by",
+					Value: "count",
+					Usage: "sort aspect ratios by count or ratio",
+				},
+				&cli.Float64Flag{
+					Name:  "max-size",
+					Usage: "fail if an image is larger than this many megabytes",
+				},
+				&cli.Float64Flag{
+					Name:  "max-total-size",
+					Usage: "fail if all audited images are larger than this many megabytes",
+				},
+				&cli.IntFlag{
+					Name:  "max-width",
+					Usage: "fail if an image is wider than this many pixels",
+				},
+				&cli.IntFlag{
+					Name:  "max-height",
+					Usage: "fail if an image is taller than this many pixels",
+				},
+				&cli.IntFlag{
+					Name:  "min-width",
+					Usage: "fail if an image is narrower than this many pixels",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				regionID := c.String("region-id")
+				format := c.String("format")
+				sortBy := c.String("sort-by")
+
+				if regionID == "" {
+					return fmt.Errorf("region id is empty")
+				}
+
+				var filter audit.Filter
+				switch kind := c.String("kind"); kind {
+				case "all":
+				case "images":
+					filter.Kind = "image"
+				case "icons":
+					filter.Kind = "icon"
+				default:
+					return fmt.Errorf("unknown kind %#v (want images, icons or all)", kind)
+				}
+				switch tier := c.String("tier"); tier {
+				case "all":
+				case "original", "compressed":
+					filter.Tier = tier
+				default:
+					return fmt.Errorf("unknown tier %#v (want original, compressed or all)", tier)
+				}
+
+				budgets := audit.Budgets{
+					MaxSize:      int64(c.Float64("max-size") * 1000 * 1000),
+					MaxTotalSize: int64(c.Float64("max-total-size") * 1000 * 1000),
+					MaxWidth:     c.Int("max-width"),
+					MaxHeight:    c.Int("max-height"),
+					MinWidth:     c.Int("min-width"),
+				}
+
+				err := audit.AuditImages(regionID, filter, budgets, format, sortBy)
+				return err
+			},
+		},
+	},
+}
+
+var locateCommand = cli.Command{
+	Name:  "locate",
+	Usage: "compare coordinates of places with GPS positions of their photos",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "region-id",
+			Aliases: []string{"id"},
+			Usage:   "region whose places will be checked",
+		},
+		&cli.StringFlag{
+			Name:  "place",
+			Usage: "check only the place with this ID",
+		},
+		&cli.Float64Flag{
+			Name:  "threshold",
+			Value: 100,
+			Usage: "distance in meters beyond which coordinates are reported as mismatched",
+		},
+		&cli.BoolFlag{
+			Name:  "write",
+			Value: false,
+			Usage: "write suggested coordinates of mismatched places to their data.json",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		regionID := c.String("region-id")
+		placeID := c.String("place")
+		threshold := c.Float64("threshold")
+		write := c.Bool("write")
+
+		if regionID == "" {
+			return fmt.Errorf("region id is empty")
+		}
+
+		err := locate.Check(regionID, placeID, threshold, write)
+		return err
+	},
+}
+
+var i18nCommand = cli.Command{
+	Name:  "i18n",
+	Usage: "exchange texts of region's datafile source with translators as gettext PO files",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "export",
+			Usage: "export texts and their existing translations to a PO file",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "region-id",
+					Aliases: []string{"id"},
+					Usage:   "region whose texts will be exported",
+				},
+				&cli.StringFlag{
+					Name:  "from",
+					Value: "pl",
+					Usage: "language of the source texts",
+				},
+				&cli.StringFlag{
+					Name:  "to",
+					Usage: "language to translate to",
+				},
+				&cli.StringFlag{
+					Name:    "output",
+					Aliases: []string{"o"},
+					Usage:   "file to write the PO file to (default is stdout)",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				regionID := c.String("region-id")
+				from := c.String("from")
+				to := c.String("to")
+				output := c.String("output")
+
+				if regionID == "" {
+					return fmt.Errorf("region id is empty")
+				}
+
+				err := i18n.Export(regionID, from, to, output)
+				return err
+			},
+		},
+		{
+			Name:      "import",
+			Usage:     "write translations from a PO file to the datafile source",
+			ArgsUsage: "FILE",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "region-id",
+					Aliases: []string{"id"},
+					Usage:   "region whose texts will be translated",
+				},
+				&cli.BoolFlag{
+					Name:  "allow-stale",
+					Usage: "import translations even if their source text changed since the export",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				regionID := c.String("region-id")
+				allowStale := c.Bool("allow-stale")
+
+				if regionID == "" {
+					return fmt.Errorf("region id is empty")
+				}
+
+				if c.NArg() != 1 {
+					return fmt.Errorf("expected 1 argument (FILE), got %d", c.NArg())
+				}
+
+				err := i18n.Import(regionID, c.Args().First(), allowStale)
+				return err
+			},
+		},
+	},
+}
+
+var diffCommand = cli.Command{
+	Name:  "diff",
+	Usage: "show changes between two versions of a datafile",
+	Description: "OLD and NEW are generated directories (generated/rudy), compressed datafiles (compressed/rudy.zip),\n" +
+		"datafile sources (datafiles/datafile-rudy) or datafile sources at a git revision (datafiles/datafile-rudy@v1.2.0)",
+	ArgsUsage: "OLD NEW",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "format of the output (text or json)",
+		},
+		&cli.IntFlag{
+			Name:    "jobs",
+			Aliases: []string{"j"},
+			Value:   runtime.NumCPU(),
+			Usage:   "number of places parsed at the same time",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		format := c.String("format")
+		jobs := c.Int("jobs")
+
+		if c.NArg() != 2 {
+			return fmt.Errorf("expected 2 arguments (OLD and NEW), got %d", c.NArg())
+		}
+
+		if jobs < 1 {
+			return fmt.Errorf("jobs must be at least 1")
+		}
+
+		err := diff.Diff(c.Args().Get(0), c.Args().Get(1), format, jobs)
+		return err
+	},
+}
+
+var schemaCommand = cli.Command{
+	Name:  "schema",
+	Usage: "work with the JSON schema of data.json",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "generate",
+			Usage: "generate the schema from the models",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "output",
+					Aliases: []string{"o"},
+					Usage:   "file to write the schema to (default is stdout)",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				output := c.String("output")
+
+				err := schema.Write(output)
+				return err
+			},
+		},
+		{
+			Name:  "check",
+			Usage: "check a generated datafile against the schema",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "region-id",
+					Aliases: []string{"id"},
+					Usage:   "region whose generated datafile will be checked",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				regionID := c.String("region-id")
+
+				if regionID == "" {
+					return fmt.Errorf("region id is empty")
+				}
+
+				err := schema.Check(regionID)
+				return err
+			},
+		},
+	},
+}
+
 var compressCommand = cli.Command{
 	Name:  "compress",
 	Usage: "make a zip archive from a generated region directory",
@@ -65,6 +466,10 @@ var compressCommand = cli.Command{
 			Value:   "",
 			Usage:   "region whose generated directory will be compressed",
 		},
+		&cli.StringSliceFlag{
+			Name:  "lang",
+			Usage: "additionally compress the region's single-language pack in this language",
+		},
 		&cli.BoolFlag{
 			Name:    "verbose",
 			Aliases: []string{"v"},
@@ -74,15 +479,14 @@ var compressCommand = cli.Command{
 	},
 	Action: func(c *cli.Context) error {
 		regionID := c.String("region-id")
+		langs := c.StringSlice("lang")
 		verbose := c.Bool("verbose")
 
 		if regionID == "" {
 			return fmt.Errorf("region id is empty")
 		}
 
-		compress.Compress(regionID, verbose)
-
-		return nil
+		return compress.Compress(regionID, langs, verbose)
 	},
 }
 
@@ -142,13 +546,48 @@ var uploadCommand = cli.Command{
 
 var optimizeCommand = cli.Command{
 	Name:  "optimize",
-	Usage: "generate optimized images for a particular place",
+	Usage: "generate optimized images for a particular place, or for a whole region",
 	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "region-id",
+			Aliases: []string{"id"},
+			Usage:   "optimize images of every section, place, track and story of this region instead of the current place",
+		},
+		&cli.IntFlag{
+			Name:    "jobs",
+			Aliases: []string{"j"},
+			Value:   runtime.NumCPU(),
+			Usage:   "number of images optimized at once (with --region-id)",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Value: false,
+			Usage: "optimize images again even if they are up to date (with --region-id)",
+		},
+		&cli.StringFlag{
+			Name:  "encoder",
+			Value: optimize.AutoEncoder,
+			Usage: "backend converting images (" + strings.Join(optimize.EncoderNames, ", ") + "); auto uses ImageMagick, go writes lossless images which are often bigger than the originals",
+		},
 		&cli.BoolFlag{
 			Name:  "no-icons",
 			Value: false,
 			Usage: "don't optimize icons",
 		},
+		&cli.BoolFlag{
+			Name:  "crop-icons",
+			Value: false,
+			Usage: "crop non-square icons to a square around their focus point (from images/original/" + models.FocusFile + ") or center, instead of rejecting them",
+		},
+		&cli.IntSliceFlag{
+			Name:  "icon-size",
+			Usage: "additionally make icons of this size in pixels, besides the 512 px one",
+		},
+		&cli.BoolFlag{
+			Name:  "full",
+			Value: false,
+			Usage: "also make full-resolution images without metadata (in images/" + models.Original.Dir() + "), used by the original quality",
+		},
 		&cli.BoolFlag{
 			Name:    "verbose",
 			Aliases: []string{"v"},
@@ -157,9 +596,26 @@ var optimizeCommand = cli.Command{
 		},
 	},
 	Action: func(c *cli.Context) error {
-		noIcons := c.Bool("no-icons")
+		opts := optimize.Options{
+			Icons: optimize.IconOptions{
+				Skip:  c.Bool("no-icons"),
+				Crop:  c.Bool("crop-icons"),
+				Sizes: c.IntSlice("icon-size"),
+			},
+			Full: c.Bool("full"),
+		}
 		verbose := c.Bool("verbose")
 
+		enc, err := optimize.NewEncoder(c.String("encoder"))
+		if err != nil {
+			return err
+		}
+		log.Printf("encoder: %s (%s)\n", enc.Name(), enc.Capabilities())
+
+		if regionID := c.String("region-id"); regionID != "" {
+			return optimize.OptimizeRegion(regionID, enc, opts, c.Int("jobs"), c.Bool("force"), verbose)
+		}
+
 		currentDir, err := os.Getwd()
 		if err != nil {
 			return fmt.Errorf("get current working directory: %v", err)
@@ -167,7 +623,7 @@ var optimizeCommand = cli.Command{
 
 		placeID := filepath.Base(currentDir)
 
-		err = optimize.Optimize(placeID, noIcons, verbose)
+		err = optimize.Optimize(placeID, enc, opts, verbose)
 		if err != nil {
 			return fmt.Errorf("%s: %v", placeID, err)
 		}
@@ -182,6 +638,13 @@ func main() {
 		Usage: "manage the tourist database",
 		Commands: []*cli.Command{
 			&generateCommand,
+			&validateCommand,
+			&coverageCommand,
+			&i18nCommand,
+			&locateCommand,
+			&auditCommand,
+			&diffCommand,
+			&schemaCommand,
 			&compressCommand,
 			&uploadCommand,
 			&optimizeCommand,
@@ -0,0 +1,133 @@
+package optimize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// cacheDir is where caches of optimized images are stored. It's shared with
+// the caches of generated datafiles.
+var cacheDir = filepath.Join("generated", ".cache")
+
+// cache remembers from which originals, and how, the optimized images were
+// created, so that an original touched without being changed (e.g. by git
+// checkout) isn't optimized again, and an image optimized with different
+// options or encoder is.
+type cache struct {
+	// Entries are keyed by slash-separated path of the optimized image.
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+// cacheEntry describes how an optimized image was created.
+type cacheEntry struct {
+	Hash    string        `json:"hash"` // Hash of the original.
+	Encoder string        `json:"encoder"`
+	Options EncodeOptions `json:"options"`
+}
+
+func cachePath(regionID string) string {
+	return filepath.Join(cacheDir, "optimize-"+regionID+".json")
+}
+
+// loadCache reads the cache of region's optimized images. A missing or broken
+// cache is not an error.
+func loadCache(regionID string) (*cache, error) {
+	c := &cache{Entries: make(map[string]cacheEntry)}
+
+	data, err := os.ReadFile(cachePath(regionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read cache: %w", err)
+	}
+
+	err = json.Unmarshal(data, c)
+	if err != nil || c.Entries == nil {
+		// A broken cache only makes optimization slower.
+		return &cache{Entries: make(map[string]cacheEntry)}, nil
+	}
+
+	return c, nil
+}
+
+func (c *cache) save(regionID string) error {
+	err := os.MkdirAll(cacheDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("make cache dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "	")
+	if err != nil {
+		return fmt.Errorf("marshal cache: %w", err)
+	}
+
+	return os.WriteFile(cachePath(regionID), data, 0o644)
+}
+
+// upToDate returns true if the optimized image of t exists, was created by
+// encoder with the same options, and is newer than the original or was created
+// from an original with the same content. If the original had to be hashed,
+// its hash is returned too.
+func (c *cache) upToDate(t task, encoder string) (bool, string, error) {
+	srcInfo, err := os.Stat(t.srcPath)
+	if err != nil {
+		return false, "", err
+	}
+
+	entry, ok := c.Entries[filepath.ToSlash(t.dstPath)]
+	sameOptions := ok && entry.Encoder == encoder && entry.Options == t.opts
+
+	dstInfo, err := os.Stat(t.dstPath)
+	if err == nil && sameOptions && !dstInfo.ModTime().Before(srcInfo.ModTime()) {
+		return true, "", nil
+	}
+
+	hash, hashErr := hashFile(t.srcPath)
+	if hashErr != nil {
+		return false, "", hashErr
+	}
+
+	if err != nil || !sameOptions {
+		// There's no optimized image yet, or it has to be made differently.
+		return false, hash, nil
+	}
+
+	return entry.Hash == hash, hash, nil
+}
+
+// prune removes entries of optimized images which aren't made by any of tasks,
+// e.g. because their original was removed or renamed.
+func (c *cache) prune(tasks []task) {
+	wanted := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		wanted[filepath.ToSlash(t.dstPath)] = true
+	}
+
+	for dstPath := range c.Entries {
+		if !wanted[dstPath] {
+			delete(c.Entries, dstPath)
+		}
+	}
+}
+
+func hashFile(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("hash %s: %w", name, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,68 @@
+package optimize
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "ic_wide.jpg")
+	dst := filepath.Join(dir, "ic_wide.webp")
+	writeJPEG(t, src, 1600, 1000)
+	err := os.WriteFile(dst, []byte("webp"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := hashFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := EncodeOptions{MaxWidth: 512, MaxHeight: 512, Crop: image.Rect(300, 0, 1300, 1000)}
+	c := &cache{Entries: map[string]cacheEntry{
+		filepath.ToSlash(dst): {Hash: hash, Encoder: GoEncoder, Options: opts},
+	}}
+
+	tests := []struct {
+		name    string
+		opts    EncodeOptions
+		encoder string
+		want    bool
+	}{
+		{"same", opts, GoEncoder, true},
+		{"other crop", EncodeOptions{MaxWidth: 512, MaxHeight: 512, Crop: image.Rect(600, 0, 1600, 1000)}, GoEncoder, false},
+		{"other size", EncodeOptions{MaxWidth: 128, MaxHeight: 128, Crop: opts.Crop}, GoEncoder, false},
+		{"other encoder", opts, ImageMagickEncoder, false},
+	}
+
+	for _, test := range tests {
+		got, _, err := c.upToDate(task{srcPath: src, dstPath: dst, opts: test.opts}, test.encoder)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != test.want {
+			t.Errorf("%s: got up to date %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	c := &cache{Entries: map[string]cacheEntry{
+		"images/compressed/rynek.webp": {Hash: "a"},
+		"images/compressed/stary.webp": {Hash: "b"},
+	}}
+
+	c.prune([]task{{srcPath: filepath.Join("images", "original", "rynek.jpg"), dstPath: filepath.Join("images", "compressed", "rynek.webp")}})
+
+	if _, ok := c.Entries["images/compressed/stary.webp"]; ok {
+		t.Error("entry of an image without a task wasn't pruned")
+	}
+	if _, ok := c.Entries["images/compressed/rynek.webp"]; !ok {
+		t.Error("entry of an image with a task was pruned")
+	}
+}
@@ -0,0 +1,126 @@
+package optimize
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+)
+
+// Names of encoder backends accepted by NewEncoder.
+const (
+	AutoEncoder        = "auto"
+	ImageMagickEncoder = "imagemagick"
+	GoEncoder          = "go"
+)
+
+// EncoderNames lists names accepted by NewEncoder.
+var EncoderNames = []string{AutoEncoder, ImageMagickEncoder, GoEncoder}
+
+// Encoder converts original images into optimized WEBP images.
+type Encoder interface {
+	// Name returns name of the backend, one of EncoderNames.
+	Name() string
+
+	// Capabilities returns what the backend can do on this machine.
+	Capabilities() Capabilities
+
+	// Encode writes a WEBP version of the image at srcPath to dstPath, resized
+	// according to opts. The image is rotated according to its EXIF
+	// orientation, and the written image has no metadata.
+	Encode(srcPath string, dstPath string, opts EncodeOptions) error
+}
+
+// Capabilities describes an encoder backend.
+type Capabilities struct {
+	Version string   // Version of the backend, as reported by it.
+	Reads   []string // Extensions of originals which can be decoded, e.g. ".heic".
+	Lossy   bool     // Whether EncodeOptions.Quality is honoured. Lossless encoders produce bigger images.
+}
+
+// CanRead returns true if originals with extension ext can be decoded.
+func (c Capabilities) CanRead(ext string) bool {
+	return slices.Contains(c.Reads, strings.ToLower(ext))
+}
+
+func (c Capabilities) String() string {
+	compression := "lossless"
+	if c.Lossy {
+		compression = "lossy"
+	}
+
+	return fmt.Sprintf("%s, reads %s, writes %s webp", c.Version, strings.Join(c.Reads, " "), compression)
+}
+
+// EncodeOptions tells how an image is cropped, resized and compressed.
+type EncodeOptions struct {
+	// Crop is the part of the upright image which is kept, or an empty
+	// rectangle to keep all of it. Sizes below are of the cropped image.
+	Crop image.Rectangle
+
+	// Scale is the factor by which both dimensions are multiplied. If it's 0,
+	// the image is instead resized to fit in MaxWidth x MaxHeight, keeping
+	// its aspect ratio. MaxHeight 0 means that only the width is limited.
+	Scale     float64
+	MaxWidth  int
+	MaxHeight int
+
+	// Quality is the WEBP quality between 1 and 100, or 0 for the backend's
+	// default. It's ignored by lossless encoders.
+	Quality int
+}
+
+// size returns dimensions of an image of size w x h after resizing it
+// according to opts.
+func (opts EncodeOptions) size(w int, h int) (int, int) {
+	if opts.Scale != 0 {
+		return max(1, int(float64(w)*opts.Scale+0.5)), max(1, int(float64(h)*opts.Scale+0.5))
+	}
+
+	scale := float64(opts.MaxWidth) / float64(w)
+	if opts.MaxHeight != 0 {
+		scale = min(scale, float64(opts.MaxHeight)/float64(h))
+	}
+	return max(1, int(float64(w)*scale+0.5)), max(1, int(float64(h)*scale+0.5))
+}
+
+// imageOptions are used for images other than icons (see planIcon), which
+// get 4 times smaller resolution and decreased quality.
+var imageOptions = EncodeOptions{Scale: 0.25, Quality: 75}
+
+// fullQuality is the WEBP quality of full-resolution images, which are meant
+// to be looked at closely.
+const fullQuality = 90
+
+// variantOptions returns options for the variant of an image which is width
+// pixels wide (see models.VariantWidths).
+func variantOptions(width int) EncodeOptions {
+	return EncodeOptions{MaxWidth: width, Quality: 75}
+}
+
+// NewEncoder returns the encoder backend called name. AutoEncoder picks
+// ImageMagick. It doesn't fall back to the Go encoder when ImageMagick isn't
+// installed, because its lossless photos are often bigger than the originals,
+// so it has to be chosen explicitly.
+func NewEncoder(name string) (Encoder, error) {
+	switch name {
+	case AutoEncoder, "":
+		enc, err := newImageMagick()
+		if err != nil {
+			return nil, fmt.Errorf("%v; install it, or use the %s encoder, which writes lossless and often bigger images", err, GoEncoder)
+		}
+
+		return enc, nil
+	case ImageMagickEncoder:
+		enc, err := newImageMagick()
+		if err != nil {
+			return nil, err
+		}
+
+		return enc, nil
+	case GoEncoder:
+		return newNative(), nil
+	}
+
+	return nil, fmt.Errorf("unknown encoder %#v (must be one of %s)", name, strings.Join(EncoderNames, ", "))
+}
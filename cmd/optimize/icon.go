@@ -0,0 +1,143 @@
+package optimize
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/opentouristics/database-tools/models"
+)
+
+// Sizes of icons in pixels.
+const (
+	// iconSize is the size of the optimized icon shipped with the datafile.
+	iconSize = 512
+	// recommendedIconSize is the size of originals which look sharp on all
+	// screens. Smaller originals are accepted with a warning.
+	recommendedIconSize = 1024
+)
+
+// IconOptions tells how icons are optimized.
+type IconOptions struct {
+	Skip  bool  // Don't optimize icons at all.
+	Crop  bool  // Crop non-square icons to a square instead of rejecting them.
+	Sizes []int // Sizes of additional icons, besides the 512 px one.
+}
+
+// IconSizeName returns name of the additional icon called name which is size
+// pixels wide.
+func IconSizeName(name string, size int) string {
+	return name + models.VariantSeparator + strconv.Itoa(size) + "px"
+}
+
+// focus is a point of an image which should stay visible after cropping.
+type focus struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+var center = focus{X: 0.5, Y: 0.5}
+
+// readFocus reads the focus file of originalDir. A missing file is not an
+// error.
+func readFocus(originalDir string) (map[string]focus, error) {
+	data, err := os.ReadFile(filepath.Join(originalDir, models.FocusFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]focus{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	points := make(map[string]focus)
+	err = json.Unmarshal(data, &points)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", models.FocusFile, err)
+	}
+
+	for name, point := range points {
+		if point.X < 0 || point.X > 1 || point.Y < 0 || point.Y > 1 {
+			return nil, fmt.Errorf("%s: focus point of %s must be between 0 and 1", models.FocusFile, name)
+		}
+	}
+
+	return points, nil
+}
+
+// squareAround returns the largest square in a w x h image which is centered
+// on point p, as far as the image's edges allow.
+func squareAround(w int, h int, p focus) image.Rectangle {
+	side := min(w, h)
+	x := clamp(int(p.X*float64(w))-side/2, 0, w-side)
+	y := clamp(int(p.Y*float64(h))-side/2, 0, h-side)
+
+	return image.Rect(x, y, x+side, y+side)
+}
+
+func clamp(v int, lo int, hi int) int {
+	return max(lo, min(v, hi))
+}
+
+// planIcon returns tasks optimizing icon at srcPath into compressedDir. The
+// icon must be square, unless opts.Crop is true, and at least 512x512.
+func planIcon(srcPath string, compressedDir string, opts IconOptions, points map[string]focus) ([]task, error) {
+	name := strings.TrimSuffix(filepath.Base(srcPath), filepath.Ext(srcPath))
+
+	w, h, err := orientedSize(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("get dimensions of icon %s: %v", srcPath, err)
+	}
+
+	var crop image.Rectangle
+	if w != h {
+		if !opts.Crop {
+			return nil, fmt.Errorf("icon %s is %dx%d, but it must be square (crop it, or optimize with --crop-icons)", srcPath, w, h)
+		}
+
+		point, ok := points[name]
+		if !ok {
+			point = center
+		}
+		crop = squareAround(w, h, point)
+	}
+
+	side := min(w, h)
+	size := fmt.Sprintf("%dx%d", w, h)
+	if !crop.Empty() {
+		size = fmt.Sprintf("%dx%d after cropping", side, side)
+	}
+
+	if side < iconSize {
+		return nil, fmt.Errorf("icon %s is only %s, but it must be at least %dx%d (%dx%d is recommended)", srcPath, size, iconSize, iconSize, recommendedIconSize, recommendedIconSize)
+	}
+	if side < recommendedIconSize {
+		log.Printf("warning: icon %s is only %s, it may look blurry (%dx%d is recommended)\n", srcPath, size, recommendedIconSize, recommendedIconSize)
+	}
+
+	tasks := []task{{
+		srcPath: srcPath,
+		dstPath: filepath.Join(compressedDir, name+".webp"),
+		opts:    EncodeOptions{MaxWidth: iconSize, MaxHeight: iconSize, Crop: crop},
+	}}
+
+	for _, extra := range opts.Sizes {
+		if extra > side {
+			log.Printf("warning: icon %s is only %s, not making a %d px icon from it\n", srcPath, size, extra)
+			continue
+		}
+
+		tasks = append(tasks, task{
+			srcPath: srcPath,
+			dstPath: filepath.Join(compressedDir, IconSizeName(name, extra)+".webp"),
+			opts:    EncodeOptions{MaxWidth: extra, MaxHeight: extra, Crop: crop},
+			variant: true,
+		})
+	}
+
+	return tasks, nil
+}
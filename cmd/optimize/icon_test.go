@@ -0,0 +1,73 @@
+package optimize
+
+import (
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeJPEG(t *testing.T, path string, w int, h int) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	err = jpeg.Encode(file, image.NewGray(image.Rect(0, 0, w, h)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlanIcon(t *testing.T) {
+	dir := t.TempDir()
+	square := filepath.Join(dir, "ic_square.jpg")
+	wide := filepath.Join(dir, "ic_wide.jpg")
+	small := filepath.Join(dir, "ic_small.jpg")
+	writeJPEG(t, square, 1024, 1024)
+	writeJPEG(t, wide, 1600, 1000)
+	writeJPEG(t, small, 400, 400)
+
+	got, err := planIcon(square, "out", IconOptions{Sizes: []int{128, 2048}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []task{
+		{srcPath: square, dstPath: filepath.Join("out", "ic_square.webp"), opts: EncodeOptions{MaxWidth: 512, MaxHeight: 512}},
+		{srcPath: square, dstPath: filepath.Join("out", "ic_square@128px.webp"), opts: EncodeOptions{MaxWidth: 128, MaxHeight: 128}, variant: true},
+	}
+	if !cmp.Equal(got, want, cmp.AllowUnexported(task{})) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	_, err = planIcon(wide, "out", IconOptions{}, nil)
+	if err == nil {
+		t.Error("got no error for a non-square icon")
+	}
+
+	got, err = planIcon(wide, "out", IconOptions{Crop: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Rect(300, 0, 1300, 1000); got[0].opts.Crop != want {
+		t.Errorf("got crop %v around center, want %v", got[0].opts.Crop, want)
+	}
+
+	got, err = planIcon(wide, "out", IconOptions{Crop: true}, map[string]focus{"ic_wide": {X: 0.9, Y: 0.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Rect(600, 0, 1600, 1000); got[0].opts.Crop != want {
+		t.Errorf("got crop %v around focus point, want %v", got[0].opts.Crop, want)
+	}
+
+	_, err = planIcon(small, "out", IconOptions{}, nil)
+	if err == nil {
+		t.Error("got no error for a low-resolution icon")
+	}
+}
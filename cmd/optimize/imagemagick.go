@@ -0,0 +1,130 @@
+package optimize
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// formatExts maps names of ImageMagick's formats to extensions of originals.
+var formatExts = map[string][]string{
+	"JPEG": {".jpg", ".jpeg"},
+	"PNG":  {".png"},
+	"HEIC": {".heic"},
+}
+
+// imageMagick encodes images by running ImageMagick. Version 7 is run as
+// "magick", and version 6 as "convert".
+type imageMagick struct {
+	path string
+	caps Capabilities
+}
+
+func newImageMagick() (*imageMagick, error) {
+	path, err := exec.LookPath("magick")
+	if err != nil {
+		path, err = exec.LookPath("convert")
+	}
+	if err != nil {
+		return nil, errors.New("ImageMagick is not installed (neither magick nor convert is in PATH)")
+	}
+
+	enc := &imageMagick{path: path}
+
+	out, err := enc.run("-version")
+	if err != nil {
+		return nil, err
+	}
+	version, _, _ := strings.Cut(string(out), "\n")
+	enc.caps.Version = strings.TrimSpace(strings.TrimPrefix(version, "Version:"))
+	enc.caps.Lossy = true
+
+	out, err = enc.run("-list", "format")
+	if err != nil {
+		return nil, err
+	}
+
+	formats := parseFormats(out)
+	if !strings.Contains(formats["WEBP"], "w") {
+		return nil, fmt.Errorf("%s (%s) can't write WEBP images", path, enc.caps.Version)
+	}
+
+	for _, format := range []string{"JPEG", "PNG", "HEIC"} {
+		if strings.Contains(formats[format], "r") {
+			enc.caps.Reads = append(enc.caps.Reads, formatExts[format]...)
+		}
+	}
+
+	return enc, nil
+}
+
+// parseFormats returns modes (e.g. "rw+") of formats listed by
+// "convert -list format", keyed by name of the format.
+func parseFormats(out []byte) map[string]string {
+	formats := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// E.g. "     JPEG* JPEG      rw-   Joint Photographic Experts Group JFIF format"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+
+		mode := fields[2]
+		if strings.Trim(mode, "rw+-") != "" {
+			continue
+		}
+
+		formats[strings.TrimRight(fields[0], "*")] = mode
+	}
+
+	return formats
+}
+
+func (enc *imageMagick) run(args ...string) ([]byte, error) {
+	out, err := exec.Command(enc.path, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("run %s %s: %v: %s", enc.path, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+
+	return out, nil
+}
+
+func (enc *imageMagick) Name() string {
+	return ImageMagickEncoder
+}
+
+func (enc *imageMagick) Capabilities() Capabilities {
+	return enc.caps
+}
+
+func (enc *imageMagick) Encode(srcPath string, dstPath string, opts EncodeOptions) error {
+	resize := fmt.Sprintf("%dx%d", opts.MaxWidth, opts.MaxHeight)
+	if opts.MaxHeight == 0 {
+		resize = fmt.Sprintf("%dx", opts.MaxWidth)
+	}
+	if opts.Scale != 0 {
+		resize = strconv.FormatFloat(opts.Scale*100, 'f', -1, 64) + "%"
+	}
+
+	// The image is rotated according to its EXIF orientation before metadata
+	// (including GPS position) is stripped.
+	args := []string{srcPath, "-auto-orient"}
+	if !opts.Crop.Empty() {
+		crop := fmt.Sprintf("%dx%d+%d+%d", opts.Crop.Dx(), opts.Crop.Dy(), opts.Crop.Min.X, opts.Crop.Min.Y)
+		args = append(args, "-crop", crop, "+repage")
+	}
+	args = append(args, "-strip", "-resize", resize)
+	if opts.Quality != 0 {
+		args = append(args, "-quality", strconv.Itoa(opts.Quality))
+	}
+	args = append(args, "webp:"+dstPath)
+
+	_, err := enc.run(args...)
+	return err
+}
@@ -0,0 +1,146 @@
+package optimize
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"runtime"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/opentouristics/database-tools/exif"
+	"golang.org/x/image/draw"
+)
+
+// native encodes images without external programs. Originals are decoded with
+// Go decoders, rotated according to their EXIF orientation, resampled with
+// Catmull-Rom and encoded as lossless WEBP, which never carries metadata.
+type native struct{}
+
+func newNative() *native {
+	return &native{}
+}
+
+func (enc *native) Name() string {
+	return GoEncoder
+}
+
+func (enc *native) Capabilities() Capabilities {
+	return Capabilities{
+		Version: "Go " + runtime.Version(),
+		Reads:   []string{".jpg", ".jpeg", ".png", ".heic"},
+		Lossy:   false,
+	}
+}
+
+func (enc *native) Encode(srcPath string, dstPath string, opts EncodeOptions) error {
+	src, err := decodeImage(srcPath)
+	if err != nil {
+		return err
+	}
+
+	orientation := 1
+	metadata, err := exif.Read(srcPath)
+	if err == nil {
+		orientation = metadata.Orientation
+	}
+
+	// Cropping is rare, so the image is simply made upright first.
+	if !opts.Crop.Empty() {
+		upright := image.NewNRGBA(src.Bounds())
+		draw.Draw(upright, upright.Bounds(), src, src.Bounds().Min, draw.Src)
+		src = orient(upright, orientation).SubImage(opts.Crop)
+		orientation = 1
+	}
+
+	// The image is scaled before it's rotated, because it's smaller then.
+	swap := orientation >= 5
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if swap {
+		w, h = h, w
+	}
+	w, h = opts.size(w, h)
+	if swap {
+		w, h = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+	return writeWebP(dstPath, orient(dst, orientation))
+}
+
+// orient returns img transformed so that an image with EXIF orientation is
+// upright.
+func orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	// Where the pixel at x, y ends up.
+	target := func(x int, y int) (int, int) {
+		switch orientation {
+		case 2:
+			return w - 1 - x, y
+		case 3:
+			return w - 1 - x, h - 1 - y
+		case 4:
+			return x, h - 1 - y
+		case 5:
+			return y, x
+		case 6:
+			return h - 1 - y, x
+		case 7:
+			return h - 1 - y, w - 1 - x
+		default:
+			return y, w - 1 - x
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := target(x, y)
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], img.Pix[img.PixOffset(x, y):img.PixOffset(x, y)+4])
+		}
+	}
+
+	return dst
+}
+
+func decodeImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	return img, nil
+}
+
+func writeWebP(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = nativewebp.Encode(file, img, nil)
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("encode %s: %w", path, err)
+	}
+
+	return file.Close()
+}
@@ -6,18 +6,36 @@ import (
 	"fmt"
 	"image"
 	_ "image/jpeg"
+	_ "image/png"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync/atomic"
 
 	"github.com/jdeng/goheif"
+	"github.com/opentouristics/database-tools/exif"
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/parallel"
 )
 
+// originalExts are extensions of original images which can be optimized.
+var originalExts = []string{".jpg", ".jpeg", ".heic", ".png"}
+
+// Options tells which optimized versions of images are made.
+type Options struct {
+	Icons IconOptions
+
+	// Full makes also full-resolution versions of images in the "full"
+	// directory (see models.Original), so that originals with their metadata
+	// are never shipped.
+	Full bool
+}
+
 // Optimize creates optimized versions of images from images in the place's
-// "original" directory. placePath must point to a valid place.
-func Optimize(placeID string, noIcons bool, verbose bool) error {
+// "original" directory using enc. placePath must point to a valid place.
+func Optimize(placeID string, enc Encoder, opts Options, verbose bool) error {
 	// Make srcPath - either .jpg or .heic
 	originalIconPath := fmt.Sprintf("images/original/ic_%s.jpg", placeID)
 	_, err := os.Stat(originalIconPath)
@@ -29,64 +47,412 @@ func Optimize(placeID string, noIcons bool, verbose bool) error {
 		}
 	}
 
-	err = verifyValidDirectoryStructure(placeID, originalIconPath, noIcons, verbose)
+	err = verifyValidDirectoryStructure(placeID, originalIconPath, opts.Icons.Skip, verbose)
 	if err != nil {
 		return fmt.Errorf("no valid directory structure: %v", err)
 	}
 
-	if !noIcons {
-		compressedIconPath := fmt.Sprintf("images/compressed/ic_%s.webp", placeID)
-		err = makeIcon(originalIconPath, compressedIconPath)
+	tasks, err := planDir(".", opts)
+	if err != nil {
+		return err
+	}
+
+	err = checkReadable(tasks, enc)
+	if err != nil {
+		return err
+	}
+
+	// Everything is optimized again, because there's no cache outside of a
+	// region.
+	s, err := run(tasks, enc, 1, nil, true, verbose)
+	reportGPS(s.withGPS)
+
+	return err
+}
+
+// OptimizeRegion creates optimized versions of original images of every
+// section, place, track and story of region's datafile using enc. Images are
+// converted by at most jobs goroutines at once.
+//
+// Images whose optimized version is newer than the original, or whose original
+// hasn't changed since it was last optimized, are skipped unless force is
+// true. Images optimized with a different encoder or different options (e.g.
+// crop or size) are always optimized again.
+func OptimizeRegion(regionID string, enc Encoder, opts Options, jobs int, force bool, verbose bool) error {
+	datafileDir := filepath.Join("datafiles", "datafile-"+regionID)
+	if _, err := os.Stat(datafileDir); err != nil {
+		return fmt.Errorf("stat datafile's directory: %w", err)
+	}
+
+	dirs, err := entityDirs(datafileDir)
+	if err != nil {
+		return err
+	}
+
+	tasks := make([]task, 0)
+	errs := make([]error, 0)
+	for _, dir := range dirs {
+		dirTasks, err := planDir(dir, opts)
 		if err != nil {
-			return fmt.Errorf("make optimized icon: %v", err)
+			errs = append(errs, err)
+			continue
 		}
 
-		if verbose {
-			log.Println("created optimized icon")
+		tasks = append(tasks, dirTasks...)
+	}
+
+	// All problems with originals are reported before anything is converted.
+	err = errors.Join(errs...)
+	if err != nil {
+		return err
+	}
+
+	err = checkReadable(tasks, enc)
+	if err != nil {
+		return err
+	}
+
+	c, err := loadCache(regionID)
+	if err != nil {
+		return fmt.Errorf("load cache: %v", err)
+	}
+
+	s, err := run(tasks, enc, jobs, c, force, verbose)
+	c.prune(tasks)
+
+	// The cache is saved even if some images failed, so that the ones that
+	// succeeded aren't optimized again.
+	saveErr := c.save(regionID)
+	if err != nil {
+		return err
+	}
+	if saveErr != nil {
+		return fmt.Errorf("save cache: %v", saveErr)
+	}
+
+	saved := "saved " + formatBytes(s.originalBytes-s.optimizedBytes)
+	if s.optimizedBytes > s.originalBytes {
+		saved = "grew by " + formatBytes(s.optimizedBytes-s.originalBytes)
+	}
+	log.Printf("optimized %d images and %d variants (%d up to date), %s\n", s.optimized, s.variants, s.skipped, saved)
+	reportGPS(s.withGPS)
+
+	return nil
+}
+
+// reportGPS warns about originals which carry the GPS position. Optimized
+// images never do, but the originals shouldn't be shared either.
+func reportGPS(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+
+	log.Printf("%d originals carry GPS position (it was stripped from optimized images):\n", len(paths))
+	for _, path := range paths {
+		log.Println("  " + path)
+	}
+}
+
+// entityDirs returns directories of all sections, places, tracks and stories
+// of the datafile at datafileDir which have original images.
+func entityDirs(datafileDir string) ([]string, error) {
+	patterns := []string{
+		filepath.Join(datafileDir, "sections", "*"),
+		filepath.Join(datafileDir, "sections", "*", "places", "*"),
+		filepath.Join(datafileDir, "tracks", "*"),
+		filepath.Join(datafileDir, "stories", "*"),
+	}
+
+	dirs := make([]string, 0)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(filepath.Join(match, "images", "original"))
+			if err == nil && info.IsDir() {
+				dirs = append(dirs, match)
+			}
+		}
+	}
+
+	return dirs, nil
+}
+
+// task is a single image to be optimized.
+type task struct {
+	srcPath string
+	dstPath string
+	opts    EncodeOptions
+	variant bool // Whether dstPath is an additional version of the image (a smaller variant, icon size or full-resolution image).
+}
+
+// planDir returns tasks optimizing every original image in directory dir of a
+// section, place, track or story. Files starting with "ic_" are icons (see
+// planIcon). Other images also get variants (see models.VariantWidths)
+// narrower than the original. If opts.Full is true, every image and icon also
+// gets a full-resolution version.
+func planDir(dir string, opts Options) ([]task, error) {
+	originalDir := filepath.Join(dir, "images", "original")
+	compressedDir := filepath.Join(dir, "images", "compressed")
+	fullDir := filepath.Join(dir, "images", models.Original.Dir())
+
+	dirEntries, err := os.ReadDir(originalDir)
+	if err != nil {
+		return nil, fmt.Errorf("read %s directory: %v", originalDir, err)
+	}
+
+	err = os.MkdirAll(compressedDir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("create %s directory: %v", compressedDir, err)
+	}
+
+	if opts.Full {
+		err = os.MkdirAll(fullDir, 0o755)
+		if err != nil {
+			return nil, fmt.Errorf("create %s directory: %v", fullDir, err)
 		}
 	}
 
-	dirEntries, err := os.ReadDir("images/original")
+	points, err := readFocus(originalDir)
 	if err != nil {
-		return fmt.Errorf("read images/original/ directory: %v", err)
+		return nil, fmt.Errorf("%s: %v", originalDir, err)
 	}
 
+	tasks := make([]task, 0, len(dirEntries))
+	errs := make([]error, 0)
 	for _, dirEntry := range dirEntries {
-		if strings.HasPrefix(dirEntry.Name(), ".") {
+		fullName := dirEntry.Name()
+		if dirEntry.IsDir() || strings.HasPrefix(fullName, ".") {
 			continue
 		}
 
-		if strings.HasPrefix(dirEntry.Name(), "ic_") {
+		ext := filepath.Ext(fullName)
+		if !slices.Contains(originalExts, strings.ToLower(ext)) {
 			continue
 		}
 
-		fullName := dirEntry.Name()
-		name := strings.TrimSuffix(fullName, filepath.Ext(fullName))
+		srcPath := filepath.Join(originalDir, fullName)
+		name := strings.TrimSuffix(fullName, ext)
+		if strings.Contains(name, models.VariantSeparator) {
+			errs = append(errs, fmt.Errorf("%s: name must not contain %q, which separates names of images from sizes of their variants", srcPath, models.VariantSeparator))
+			continue
+		}
+
+		if strings.HasPrefix(fullName, "ic_") {
+			if opts.Icons.Skip {
+				continue
+			}
+
+			iconTasks, err := planIcon(srcPath, compressedDir, opts.Icons, points)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			tasks = append(tasks, iconTasks...)
+			continue
+		}
+
+		tasks = append(tasks, task{srcPath: srcPath, dstPath: filepath.Join(compressedDir, name+".webp"), opts: imageOptions})
 
-		// Make srcPath - either .jpg or .heic
-		srcPath := fmt.Sprintf("images/original/%s.jpg", name)
-		_, err := os.Stat(srcPath)
+		width, _, err := orientedSize(srcPath)
 		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				srcPath = fmt.Sprintf("images/original/%s.heic", name)
-			} else {
-				return fmt.Errorf("stat %s: %v", srcPath, err)
+			errs = append(errs, fmt.Errorf("get dimensions of %s: %v", srcPath, err))
+			continue
+		}
+
+		for _, variantWidth := range models.VariantWidths {
+			if variantWidth >= width {
+				break
+			}
+
+			tasks = append(tasks, task{
+				srcPath: srcPath,
+				dstPath: filepath.Join(compressedDir, models.VariantName(name, variantWidth)+".webp"),
+				opts:    variantOptions(variantWidth),
+				variant: true,
+			})
+		}
+	}
+
+	if opts.Full {
+		tasks = append(tasks, fullTasks(tasks, fullDir)...)
+	}
+
+	return tasks, errors.Join(errs...)
+}
+
+// fullTasks returns tasks making full-resolution versions of images optimized
+// by main tasks (not variants) of tasks into fullDir. Icons keep their crop.
+func fullTasks(tasks []task, fullDir string) []task {
+	full := make([]task, 0)
+	for _, t := range tasks {
+		if t.variant {
+			continue
+		}
+
+		full = append(full, task{
+			srcPath: t.srcPath,
+			dstPath: filepath.Join(fullDir, filepath.Base(t.dstPath)),
+			opts:    EncodeOptions{Crop: t.opts.Crop, Scale: 1, Quality: fullQuality},
+			variant: true,
+		})
+	}
+
+	return full
+}
+
+// orientedSize returns dimensions of the image at path after it's rotated
+// according to its EXIF orientation.
+func orientedSize(path string) (int, int, error) {
+	w, h, err := getImageDimensions(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	metadata, err := exif.Read(path)
+	if err == nil && metadata.Orientation >= 5 {
+		return h, w, nil
+	}
+
+	return w, h, nil
+}
+
+// checkReadable returns an error listing originals of tasks which enc can't
+// decode, so that it's known before anything is converted.
+func checkReadable(tasks []task, enc Encoder) error {
+	caps := enc.Capabilities()
+
+	errs := make([]error, 0)
+	for _, t := range tasks {
+		if !caps.CanRead(filepath.Ext(t.srcPath)) {
+			errs = append(errs, fmt.Errorf("%s: %s encoder can't read %s images", t.srcPath, enc.Name(), filepath.Ext(t.srcPath)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// summary tells what happened during optimization.
+type summary struct {
+	optimized      int
+	variants       int // Optimized variants, not counted in optimized.
+	skipped        int
+	originalBytes  int64 // Total size of optimized originals.
+	optimizedBytes int64 // Total size of their optimized versions, without variants.
+
+	withGPS []string // Originals with GPS position in their EXIF metadata, including skipped ones.
+}
+
+// run performs tasks with enc using at most jobs goroutines. If c is not nil,
+// performed tasks are recorded in it, and unless force is true, tasks which are
+// up to date according to it are skipped.
+func run(tasks []task, enc Encoder, jobs int, c *cache, force bool, verbose bool) (summary, error) {
+	var (
+		s                             summary
+		optimized, variants, skipped  atomic.Int64
+		originalBytes, optimizedBytes atomic.Int64
+	)
+
+	hashes := make([]string, len(tasks))
+	gps := make([]bool, len(tasks))
+	err := parallel.ForEach(len(tasks), jobs, func(i int) error {
+		t := tasks[i]
+
+		// Broken metadata doesn't prevent optimization.
+		metadata, err := exif.Read(t.srcPath)
+		gps[i] = err == nil && metadata.GPS != nil && !t.variant
+
+		if c != nil && force {
+			hash, err := hashFile(t.srcPath)
+			if err != nil {
+				return err
+			}
+			hashes[i] = hash
+		} else if c != nil {
+			upToDate, hash, err := c.upToDate(t, enc.Name())
+			if err != nil {
+				return err
+			}
+			hashes[i] = hash
+
+			if upToDate {
+				skipped.Add(1)
+				return nil
 			}
 		}
 
-		dstPath := fmt.Sprintf("images/compressed/%s.webp", name)
+		err = enc.Encode(t.srcPath, t.dstPath, t.opts)
+		if err != nil {
+			return fmt.Errorf("optimize %s: %v", t.srcPath, err)
+		}
 
-		err = makeImage(srcPath, dstPath)
+		srcInfo, err := os.Stat(t.srcPath)
 		if err != nil {
-			return fmt.Errorf("create optimized image %s: %v", name, err)
+			return err
+		}
+		dstInfo, err := os.Stat(t.dstPath)
+		if err != nil {
+			return err
+		}
+
+		if t.variant {
+			variants.Add(1)
+		} else {
+			optimized.Add(1)
+			originalBytes.Add(srcInfo.Size())
+			optimizedBytes.Add(dstInfo.Size())
+
+			// Lossless encoders can make photos bigger.
+			if dstInfo.Size() > srcInfo.Size() {
+				log.Printf("warning: optimized %s (%s) is bigger than its original (%s)\n", t.dstPath, formatBytes(dstInfo.Size()), formatBytes(srcInfo.Size()))
+			}
 		}
 
 		if verbose {
-			log.Println("created optimized image", name)
+			log.Printf("optimized %s (%s) to %s (%s)\n", t.srcPath, formatBytes(srcInfo.Size()), filepath.Base(t.dstPath), formatBytes(dstInfo.Size()))
+		}
+
+		return nil
+	})
+
+	if c != nil {
+		for i, t := range tasks {
+			if hashes[i] != "" {
+				if _, err := os.Stat(t.dstPath); err == nil {
+					c.Entries[filepath.ToSlash(t.dstPath)] = cacheEntry{Hash: hashes[i], Encoder: enc.Name(), Options: t.opts}
+				}
+			}
 		}
 	}
 
-	return nil
+	for i, t := range tasks {
+		if gps[i] {
+			s.withGPS = append(s.withGPS, t.srcPath)
+		}
+	}
+
+	s.optimized = int(optimized.Load())
+	s.variants = int(variants.Load())
+	s.skipped = int(skipped.Load())
+	s.originalBytes = originalBytes.Load()
+	s.optimizedBytes = optimizedBytes.Load()
+
+	return s, err
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20 || n <= -1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10 || n <= -1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
 }
 
 func verifyValidDirectoryStructure(
@@ -126,15 +492,7 @@ func verifyValidDirectoryStructure(
 			}
 		}
 
-		// TODO: check if that JPG icon is 1024x1024
-		w, h, err := getImageDimensions(originalIconPath)
-		if err != nil {
-			return fmt.Errorf("get image dimensions: %w", err)
-		}
-
-		if w != 1024 && h != 1024 {
-			return fmt.Errorf("dimensions of %s are not 1024x1024", originalIconPath)
-		}
+		// Dimensions of the icon are checked by planIcon.
 	}
 
 	// Does images/compressed/ directory exist?
@@ -157,36 +515,12 @@ func verifyValidDirectoryStructure(
 	return nil
 }
 
-// MakeIcons creates a compressed 512x512 WEBP version of an original 1024x1024
-// JPG or HEIC icon.
-func makeIcon(srcPath string, dstPath string) error {
-	cmd := exec.Command("convert", srcPath, "-resize", "512x512", dstPath)
-	err := cmd.Run()
-	if err != nil {
-		return fmt.Errorf("run ImageMagick for icon at %s: %w", srcPath, err)
-	}
-
-	return nil
-}
-
-// MakeImage creates a compressed WEBP version of an original JPEG or HEIC
-// image. The compressed image has 4 times smaller resolution and also has
-// decreased quality.
-func makeImage(srcPath string, dstPath string) error {
-	cmd := exec.Command("convert", srcPath, "-resize", "25%", "-quality", "75", dstPath)
-	err := cmd.Run()
-	if err != nil {
-		return fmt.Errorf("run ImageMagick for image at %s: %w", srcPath, err)
-	}
-
-	return nil
-}
-
 func getImageDimensions(imagePath string) (int, int, error) {
 	file, err := os.Open(imagePath)
 	if err != nil {
 		return 0, 0, err
 	}
+	defer file.Close()
 
 	ext := filepath.Ext(imagePath)
 	var config image.Config
@@ -0,0 +1,39 @@
+package optimize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPlanDirFull(t *testing.T) {
+	dir := t.TempDir()
+	originalDir := filepath.Join(dir, "images", "original")
+	err := os.MkdirAll(originalDir, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := filepath.Join(originalDir, "rynek.jpg")
+	writeJPEG(t, src, 200, 150)
+
+	got, err := planDir(dir, Options{Full: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The image is too narrow for any variant.
+	want := []task{
+		{srcPath: src, dstPath: filepath.Join(dir, "images", "compressed", "rynek.webp"), opts: imageOptions},
+		{srcPath: src, dstPath: filepath.Join(dir, "images", "full", "rynek.webp"), opts: EncodeOptions{Scale: 1, Quality: fullQuality}, variant: true},
+	}
+	if !cmp.Equal(got, want, cmp.AllowUnexported(task{})) {
+		t.Errorf("got tasks:\n%s", cmp.Diff(want, got, cmp.AllowUnexported(task{})))
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "images", "full")); err != nil {
+		t.Errorf("full directory wasn't created: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Package schema implements generating datafile_schema.json from the models
+// and checking generated datafiles against it.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/opentouristics/database-tools/models"
+)
+
+// Marshal returns the datafile's schema as indented JSON.
+func Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(models.DatafileSchema(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal schema to JSON: %v", err)
+	}
+
+	return append(data, '\n'), nil
+}
+
+// Write writes the datafile's schema to file at outputPath, or to stdout if
+// outputPath is empty.
+func Write(outputPath string) error {
+	data, err := Marshal()
+	if err != nil {
+		return err
+	}
+
+	if outputPath == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	err = os.WriteFile(outputPath, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("write schema to %s: %v", outputPath, err)
+	}
+
+	log.Printf("wrote schema to %s\n", outputPath)
+	return nil
+}
+
+// Check validates data.json of the region's generated datafile against the
+// datafile's schema.
+func Check(regionID string) error {
+	dataPath := filepath.Join("generated", regionID, "data.json")
+	data, err := os.ReadFile(dataPath)
+	if err != nil {
+		return fmt.Errorf("read generated datafile: %v", err)
+	}
+
+	err = models.DatafileSchema().Validate(data)
+	if err != nil {
+		return fmt.Errorf("%s does not conform to the schema:\n%v", dataPath, err)
+	}
+
+	log.Printf("%s conforms to the schema\n", dataPath)
+	return nil
+}
@@ -0,0 +1,27 @@
+package schema_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/cmd/schema"
+)
+
+// TestSchemaUpToDate fails when the models were changed, but the schema file
+// in the repository wasn't regenerated.
+func TestSchemaUpToDate(t *testing.T) {
+	want, err := schema.Marshal()
+	if err != nil {
+		t.Fatalf("failed to marshal schema: %v", err)
+	}
+
+	got, err := os.ReadFile("../../datafile_schema.json")
+	if err != nil {
+		t.Fatalf("failed to read schema file: %v", err)
+	}
+
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("datafile_schema.json is out of date, run 'touristdb schema generate -o datafile_schema.json' (-want +got):\n%s", diff)
+	}
+}
@@ -27,4 +27,38 @@ type Manifest struct {
 	ThumbURL      string            `json:"thumbURL" firestore:"thumbURL"`
 	Center        models.Location   `json:"center" firestore:"center"`
 	Bounds        []models.Location `json:"bounds" firestore:"bounds"`
+	Languages     []string          `json:"languages" firestore:"languages"`
+	LanguagePacks []LanguagePack    `json:"languagePacks" firestore:"languagePacks"`
+	Changes       *ChangesSummary   `json:"changes" firestore:"changes"`
+}
+
+// ChangesSummary tells how many places changed since the previous version of
+// the datafile, so that the app can show it before the datafile is downloaded.
+type ChangesSummary struct {
+	Since         string `json:"since" firestore:"since"`
+	NewPlaces     int    `json:"newPlaces" firestore:"newPlaces"`
+	UpdatedPlaces int    `json:"updatedPlaces" firestore:"updatedPlaces"`
+	RemovedPlaces int    `json:"removedPlaces" firestore:"removedPlaces"`
+}
+
+// summarizeChanges returns the summary of changelog, or nil if there is none.
+func summarizeChanges(changelog *models.Changelog) *ChangesSummary {
+	if changelog == nil {
+		return nil
+	}
+
+	return &ChangesSummary{
+		Since:         changelog.Since,
+		NewPlaces:     len(changelog.NewPlaces),
+		UpdatedPlaces: len(changelog.UpdatedPlaces),
+		RemovedPlaces: len(changelog.RemovedPlaces),
+	}
+}
+
+// LanguagePack describes an archive of the datafile with texts in a single
+// language.
+type LanguagePack struct {
+	Lang     string `json:"lang" firestore:"lang"`
+	FileSize int64  `json:"fileSize" firestore:"fileSize"`
+	FileURL  string `json:"fileURL" firestore:"fileURL"`
 }
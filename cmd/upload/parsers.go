@@ -1,11 +1,15 @@
 package upload
 
 import (
+	"archive/zip"
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"time"
 
-	"github.com/bbrks/go-blurhash"
 	"github.com/opentouristics/database-tools/models"
 	"golang.org/x/image/webp"
 )
@@ -14,25 +18,46 @@ import (
 func parseMeta(regionID string) (*models.Meta, error) {
 	datafilePath := filepath.Join("generated", regionID)
 
-	if err := os.Chdir(datafilePath); err != nil {
-		return nil, fmt.Errorf("chdir into generated datafile's dir at %s: %w", datafilePath, err)
-	}
-
 	var meta models.Meta
-	err := meta.ParseFromGenerated()
+	err := meta.ParseFromGenerated(os.DirFS(datafilePath))
 	if err != nil {
 		return nil, fmt.Errorf("parse meta from generated datafile's data.json at %s: %w", datafilePath, err)
 	}
 
-	err = os.Chdir("../../")
+	return &meta, nil
+}
+
+// packVersion identifies the generation a compressed pack comes from.
+type packVersion struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	CommitHash  string    `json:"commit_hash"`
+}
+
+// parsePackVersion reads the version of compressed pack packID from meta.json
+// in its zip archive at zipPath.
+func parsePackVersion(zipPath string, packID string) (packVersion, error) {
+	var version packVersion
+
+	zipReader, err := zip.OpenReader(zipPath)
 	if err != nil {
-		return nil, fmt.Errorf("exit (=chdir 2 dirs up) generated datafile's dir at %s: %w", datafilePath, err)
+		return version, err
 	}
+	defer zipReader.Close()
 
-	return &meta, nil
+	data, err := fs.ReadFile(zipReader, path.Join(packID, "meta.json"))
+	if err != nil {
+		return version, err
+	}
+
+	err = json.Unmarshal(data, &version)
+	if err != nil {
+		return version, fmt.Errorf("unmarshal meta.json: %w", err)
+	}
+
+	return version, nil
 }
 
-func makeThumbBlurhash(regionID string) (blur string, err error) {
+func makeThumbBlurhash(regionID string) (string, error) {
 	file, err := os.Open(filepath.Join("datafiles", "datafile-"+regionID, "meta", "thumb_mini.webp"))
 	if err != nil {
 		return "", err
@@ -43,10 +68,5 @@ func makeThumbBlurhash(regionID string) (blur string, err error) {
 		return "", err
 	}
 
-	blur, err = blurhash.Encode(4, 3, thumbImage)
-	if err != nil {
-		return "", nil
-	}
-
-	return
+	return models.Blurhash(thumbImage)
 }
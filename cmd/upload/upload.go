@@ -13,7 +13,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
+	"github.com/opentouristics/database-tools/models"
 	"github.com/opentouristics/database-tools/readers"
 
 	"cloud.google.com/go/firestore"
@@ -107,6 +109,9 @@ func Upload(regionID string, position int, onlyMeta bool, prod bool) error {
 		ThumbURL:      thumbLocation,
 		Center:        meta.Center,
 		Bounds:        meta.Bounds,
+		Languages:     meta.Languages,
+		LanguagePacks: findLanguagePacks(regionID, prefixedRegionID, meta),
+		Changes:       summarizeChanges(meta.Changes),
 	}
 
 	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
@@ -134,6 +139,19 @@ func Upload(regionID string, position int, onlyMeta bool, prod bool) error {
 		}()
 	}
 
+	// Upload single-language packs
+	if !onlyMeta {
+		for _, pack := range manifest.LanguagePacks {
+			packID := models.PackID(regionID, pack.Lang)
+			localPath := filepath.Join("compressed", packID+".zip")
+			cloudPath := path.Join("static", prefixedRegionID, packID+".zip")
+			err = upload(localPath, cloudPath, "application/zip")
+			if err != nil {
+				return fmt.Errorf("upload %s pack: %v", pack.Lang, err)
+			}
+		}
+	}
+
 	// Upload thumb
 	func() {
 		localPath := filepath.Join("database", regionID+"/meta/thumb.webp")
@@ -158,6 +176,41 @@ func Upload(regionID string, position int, onlyMeta bool, prod bool) error {
 	return nil
 }
 
+// findLanguagePacks returns single-language packs of the region which were
+// compressed and are ready to be uploaded. Packs left over from another
+// generation than the one described by meta are skipped with a warning.
+func findLanguagePacks(regionID string, prefixedRegionID string, meta *models.Meta) []LanguagePack {
+	packs := make([]LanguagePack, 0)
+	for _, lang := range meta.Languages {
+		packID := models.PackID(regionID, lang)
+		zipPath := filepath.Join("compressed", packID+".zip")
+		info, err := os.Stat(zipPath)
+		if err != nil {
+			continue
+		}
+
+		version, err := parsePackVersion(zipPath, packID)
+		if err != nil {
+			log.Printf("warning: skipping %s pack: parse its meta: %v\n", lang, err)
+			continue
+		}
+
+		if !version.GeneratedAt.Equal(meta.GeneratedAt) || version.CommitHash != meta.CommitHash {
+			log.Printf("warning: skipping %s pack, it was generated at %s from commit %s, not at %s from commit %s like the datafile (generate and compress it again)\n",
+				lang, version.GeneratedAt.Format(time.RFC3339), version.CommitHash, meta.GeneratedAt.Format(time.RFC3339), meta.CommitHash)
+			continue
+		}
+
+		packs = append(packs, LanguagePack{
+			Lang:     lang,
+			FileSize: info.Size(),
+			FileURL:  appspotURL + url.QueryEscape("/"+prefixedRegionID+"/"+packID+".zip") + "?alt=media",
+		})
+	}
+
+	return packs
+}
+
 // Upload uploads file at localPath (relative) to Cloud Storage at cloudPath
 // (absolute).
 func upload(localPath string, cloudPath string, contentType string) error {
@@ -0,0 +1,60 @@
+package upload
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opentouristics/database-tools/models"
+)
+
+// writePack writes compressed pack packID with meta.json containing
+// metaJSON.
+func writePack(t *testing.T, packID string, metaJSON string) {
+	file, err := os.Create(filepath.Join("compressed", packID+".zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	zipWriter := zip.NewWriter(file)
+	w, err := zipWriter.Create(packID + "/meta.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Write([]byte(metaJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = zipWriter.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindLanguagePacks(t *testing.T) {
+	t.Chdir(t.TempDir())
+	err := os.Mkdir("compressed", 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writePack(t, "rudy.pl", `{"generated_at": "2026-10-18T12:00:00Z", "commit_hash": "abc1234"}`)
+	writePack(t, "rudy.en", `{"generated_at": "2026-09-01T12:00:00Z", "commit_hash": "abc1234"}`)
+	writePack(t, "rudy.de", `{"generated_at": "2026-10-18T12:00:00Z", "commit_hash": "0123abc"}`)
+
+	meta := &models.Meta{
+		GeneratedAt: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		CommitHash:  "abc1234",
+		Languages:   []string{"pl", "en", "de", "cs"},
+	}
+
+	packs := findLanguagePacks("rudy", "rudyTest", meta)
+	if len(packs) != 1 || packs[0].Lang != "pl" {
+		t.Errorf("got packs %+v, want only the pl pack", packs)
+	}
+}
@@ -0,0 +1,106 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Severity tells how serious a problem is.
+type Severity string
+
+const (
+	// Error is a problem that makes generating the datafile impossible or
+	// produces a broken datafile.
+	Error Severity = "error"
+	// Warning is a problem that doesn't break the datafile, but should be fixed
+	// anyway, e.g a missing translation.
+	Warning Severity = "warning"
+)
+
+// Problem is a single issue found in the datafile's source.
+type Problem struct {
+	Severity Severity `json:"severity"`
+	Entity   string   `json:"entity"`    // Type of the entity, e.g "place".
+	EntityID string   `json:"entity_id"` // ID of the entity, or its directory name if ID is unknown.
+	Path     string   `json:"path"`      // Path to the file, relative to the datafile's root.
+	Message  string   `json:"message"`
+}
+
+// Report is a list of all problems found in the datafile's source.
+type Report struct {
+	Problems []Problem `json:"problems"`
+}
+
+// Errors returns the number of problems with Error severity.
+func (r *Report) Errors() int {
+	return r.count(Error)
+}
+
+// Warnings returns the number of problems with Warning severity.
+func (r *Report) Warnings() int {
+	return r.count(Warning)
+}
+
+func (r *Report) count(severity Severity) int {
+	n := 0
+	for _, problem := range r.Problems {
+		if problem.Severity == severity {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (r *Report) add(problem Problem) {
+	r.Problems = append(r.Problems, problem)
+}
+
+// WriteText writes problems to w, grouped by entity in the order in which they
+// were found.
+func (r *Report) WriteText(w io.Writer) {
+	type group struct {
+		entity   string
+		problems []Problem
+	}
+
+	groups := make([]*group, 0)
+	groupsByEntity := make(map[string]*group)
+	for _, problem := range r.Problems {
+		entity := problem.Entity + " " + problem.EntityID
+		g, ok := groupsByEntity[entity]
+		if !ok {
+			g = &group{entity: entity}
+			groupsByEntity[entity] = g
+			groups = append(groups, g)
+		}
+
+		g.problems = append(g.problems, problem)
+	}
+
+	for _, g := range groups {
+		fmt.Fprintln(w, g.entity)
+		for _, problem := range g.problems {
+			fmt.Fprintf(w, "  %s: %s: %s\n", problem.Severity, problem.Path, problem.Message)
+		}
+	}
+
+	fmt.Fprintf(w, "%d errors, %d warnings\n", r.Errors(), r.Warnings())
+}
+
+// WriteJSON writes the report as JSON to w.
+func (r *Report) WriteJSON(w io.Writer) error {
+	problems := r.Problems
+	if problems == nil {
+		problems = make([]Problem, 0)
+	}
+
+	data, err := json.MarshalIndent(Report{Problems: problems}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal report to JSON: %v", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
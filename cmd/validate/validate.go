@@ -0,0 +1,385 @@
+// Package validate implements checking the datafile's source for problems.
+// Unlike generate, it doesn't stop at the first problem, so that editors can
+// fix all of them at once.
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/opentouristics/database-tools/models"
+)
+
+// Validate checks the source of region's datafile and prints a report in
+// format ("text" or "json") to stdout. It returns an error if any problem with
+// Error severity was found.
+func Validate(regionID string, format string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown format %#v (want text or json)", format)
+	}
+
+	datafileDir := filepath.Join("datafiles", "datafile-"+regionID)
+	if _, err := os.Stat(datafileDir); err != nil {
+		return fmt.Errorf("stat datafile's directory: %w", err)
+	}
+
+	report := Check(os.DirFS(datafileDir))
+
+	if format == "json" {
+		err := report.WriteJSON(os.Stdout)
+		if err != nil {
+			return err
+		}
+	} else {
+		report.WriteText(os.Stdout)
+	}
+
+	if n := report.Errors(); n > 0 {
+		return fmt.Errorf("found %d errors in datafile %s", n, regionID)
+	}
+
+	return nil
+}
+
+// Check walks the whole source of the datafile and reports every problem it
+// finds. fsys must be rooted at the datafile's directory.
+func Check(fsys fs.FS) Report {
+	c := checker{fsys: fsys}
+
+	root := entity{kind: "datafile", id: "-", dir: "."}
+
+	policy, err := models.ReadLanguagePolicy(fsys)
+	if err != nil {
+		c.errorf(root, models.LanguagePolicyFile, "read language policy: %v", err)
+		policy = models.DefaultLanguagePolicy()
+	}
+	c.policy = policy
+
+	c.checkMeta(entity{kind: "meta", id: "meta", dir: "meta"})
+
+	for _, dir := range c.subdirs(root, "sections", true) {
+		c.checkSection(entity{kind: "section", id: path.Base(dir), dir: dir})
+	}
+
+	for _, dir := range c.subdirs(root, "tracks", false) {
+		c.checkTrack(entity{kind: "track", id: path.Base(dir), dir: dir})
+	}
+
+	for _, dir := range c.subdirs(root, "stories", false) {
+		c.checkStory(entity{kind: "story", id: path.Base(dir), dir: dir})
+	}
+
+	return c.report
+}
+
+// entity is a single section, place, track or story being checked.
+type entity struct {
+	kind string
+	id   string
+	dir  string
+}
+
+type checker struct {
+	fsys   fs.FS
+	policy models.LanguagePolicy
+	report Report
+}
+
+func (c *checker) errorf(e entity, name string, format string, args ...any) {
+	c.add(Error, e, name, format, args...)
+}
+
+func (c *checker) warnf(e entity, name string, format string, args ...any) {
+	c.add(Warning, e, name, format, args...)
+}
+
+func (c *checker) add(severity Severity, e entity, name string, format string, args ...any) {
+	c.report.add(Problem{
+		Severity: severity,
+		Entity:   e.kind,
+		EntityID: e.id,
+		Path:     path.Join(e.dir, name),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) exists(e entity, name string) bool {
+	_, err := fs.Stat(c.fsys, path.Join(e.dir, name))
+	return err == nil
+}
+
+// subdirs returns directories inside directory name of entity e. If the
+// directory doesn't exist, it is reported only if it's required.
+func (c *checker) subdirs(e entity, name string, required bool) []string {
+	dir := path.Join(e.dir, name)
+	entries, err := fs.ReadDir(c.fsys, dir)
+	if err != nil {
+		if required || !errors.Is(err, fs.ErrNotExist) {
+			c.errorf(e, name, "read directory: %v", err)
+		}
+		return nil
+	}
+
+	dirs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, path.Join(dir, entry.Name()))
+		}
+	}
+
+	return dirs
+}
+
+// readJSON unmarshals file name of entity e into v. It returns false if the
+// file couldn't be read.
+func (c *checker) readJSON(e entity, name string, v any) bool {
+	data, err := fs.ReadFile(c.fsys, path.Join(e.dir, name))
+	if err != nil {
+		c.errorf(e, name, "read file: %v", err)
+		return false
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		c.errorf(e, name, "unmarshal JSON: %v", err)
+		return false
+	}
+
+	return true
+}
+
+// languages returns languages available in the content directory of entity e.
+func (c *checker) languages(e entity) []string {
+	entries, err := fs.ReadDir(c.fsys, path.Join(e.dir, "content"))
+	if err != nil {
+		c.errorf(e, "content", "read directory: %v", err)
+		return nil
+	}
+
+	langs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			langs = append(langs, entry.Name())
+		}
+	}
+
+	return langs
+}
+
+// checkLocalized checks that filename is present in every language of entity
+// e. A file missing in a language required by the language policy is an error,
+// other missing files are warnings.
+func (c *checker) checkLocalized(e entity, langs []string, filename string) {
+	for _, lang := range langs {
+		name := path.Join("content", lang, filename)
+		if c.exists(e, name) {
+			continue
+		}
+
+		if slices.Contains(c.policy.Required, lang) {
+			c.errorf(e, name, "no %s translation", lang)
+		} else {
+			c.warnf(e, name, "no %s translation", lang)
+		}
+	}
+
+	if langs == nil {
+		return
+	}
+
+	for _, lang := range c.policy.Required {
+		if !slices.Contains(langs, lang) {
+			c.errorf(e, path.Join("content", lang, filename), "no %s translation", lang)
+		}
+	}
+}
+
+// localizedFiles returns names of files with prefix present in any language
+// of entity e, sorted.
+func (c *checker) localizedFiles(e entity, langs []string, prefix string) []string {
+	seen := make(map[string]bool)
+	for _, lang := range langs {
+		entries, err := fs.ReadDir(c.fsys, path.Join(e.dir, "content", lang))
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+				seen[entry.Name()] = true
+			}
+		}
+	}
+
+	files := make([]string, 0, len(seen))
+	for file := range seen {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+func (c *checker) checkImage(e entity, image string) {
+	if strings.Contains(image, models.VariantSeparator) {
+		c.errorf(e, "data.json", "image name %s must not contain %q, which separates names of images from sizes of their variants", image, models.VariantSeparator)
+		return
+	}
+
+	_, err := models.FindImage(c.fsys, e.dir, models.Compressed, image)
+	if err != nil {
+		name := path.Join("images", models.Compressed.Dir(), image+".webp")
+		c.errorf(e, name, "image %s does not exist", image)
+	}
+}
+
+func (c *checker) checkMeta(e entity) {
+	var meta models.Meta
+	c.readJSON(e, "data.json", &meta)
+
+	langs := c.languages(e)
+	c.checkLocalized(e, langs, "name.txt")
+}
+
+func (c *checker) checkSection(e entity) {
+	var section models.Section
+	if c.readJSON(e, "data.json", &section) {
+		if section.ID == "" {
+			c.errorf(e, "data.json", "id is empty")
+		} else {
+			e.id = section.ID
+		}
+
+		if section.BgImage != "" {
+			c.checkImage(e, section.BgImage)
+		}
+	}
+
+	langs := c.languages(e)
+	c.checkLocalized(e, langs, "name.txt")
+	c.checkLocalized(e, langs, "quick_info.txt")
+
+	for _, dir := range c.subdirs(e, "places", true) {
+		c.checkPlace(entity{kind: "place", id: path.Base(dir), dir: dir})
+	}
+}
+
+func (c *checker) checkPlace(e entity) {
+	var place models.Place
+	if c.readJSON(e, "data.json", &place) {
+		if place.ID == "" {
+			c.errorf(e, "data.json", "id is empty")
+		} else {
+			e.id = place.ID
+		}
+
+		for _, image := range place.Images {
+			c.checkImage(e, image.Name)
+		}
+
+		if place.Icon == "" {
+			c.errorf(e, "data.json", "icon is empty")
+		} else {
+			c.checkImage(e, place.Icon)
+		}
+	}
+
+	langs := c.languages(e)
+	c.checkLocalized(e, langs, "name.txt")
+	c.checkLocalized(e, langs, "quick_info.txt")
+	c.checkLocalized(e, langs, "overview.txt")
+
+	for _, textFile := range c.localizedFiles(e, langs, "text_") {
+		c.checkLocalized(e, langs, textFile)
+	}
+
+	actionFiles := c.localizedFiles(e, langs, "action_")
+	for _, actionFile := range actionFiles {
+		c.checkLocalized(e, langs, actionFile)
+	}
+
+	if !c.exists(e, "actions.json") {
+		if len(actionFiles) > 0 {
+			c.warnf(e, "actions.json", "file does not exist, but there are %d action_* files", len(actionFiles))
+		}
+		return
+	}
+
+	actionValues := make([]string, 0)
+	if !c.readJSON(e, "actions.json", &actionValues) {
+		return
+	}
+
+	if len(actionValues) != len(actionFiles) {
+		c.errorf(e, "actions.json", "has %d actions, but there are %d action_* files", len(actionValues), len(actionFiles))
+	}
+}
+
+func (c *checker) checkTrack(e entity) {
+	var track models.Track
+	if c.readJSON(e, "data.json", &track) {
+		if track.ID == "" {
+			c.errorf(e, "data.json", "id is empty")
+		} else {
+			e.id = track.ID
+		}
+
+		for _, image := range track.Images {
+			c.checkImage(e, image)
+		}
+	}
+
+	langs := c.languages(e)
+	c.checkLocalized(e, langs, "name.txt")
+	c.checkLocalized(e, langs, "quick_info.txt")
+	c.checkLocalized(e, langs, "overview.txt")
+}
+
+func (c *checker) checkStory(e entity) {
+	var story models.Story
+	ok := c.readJSON(e, "data.json", &story)
+	if ok {
+		if story.ID == "" {
+			c.errorf(e, "data.json", "id is empty")
+		} else {
+			e.id = story.ID
+		}
+	}
+
+	langs := c.languages(e)
+	c.checkLocalized(e, langs, "name.txt")
+
+	if !ok {
+		return
+	}
+
+	// Every language in which the story has a name must have its markdown.
+	for _, lang := range langs {
+		if !c.exists(e, path.Join("content", lang, "name.txt")) {
+			continue
+		}
+
+		markdownPath := path.Join("content", lang, story.MarkdownFile+".md")
+		if c.exists(e, markdownPath) {
+			continue
+		}
+
+		if slices.Contains(c.policy.Required, lang) {
+			c.errorf(e, markdownPath, "markdown file does not exist")
+		} else {
+			c.warnf(e, markdownPath, "no %s translation, but the story has %s name", lang, lang)
+		}
+	}
+
+	for _, image := range story.Images {
+		c.checkImage(e, image.Name)
+	}
+}
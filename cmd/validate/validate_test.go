@@ -0,0 +1,46 @@
+package validate_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/cmd/validate"
+	"github.com/opentouristics/database-tools/sourcetest"
+)
+
+func TestCheck(t *testing.T) {
+	fsys := sourcetest.Datafile()
+	delete(fsys, "sections/01_zabytki/places/kosciol/images/compressed/kosciol_1.webp")
+	fsys["sections/01_zabytki/places/kosciol/actions.json"] = sourcetest.File(`["https://a.example", "https://b.example"]`)
+	fsys["sections/01_zabytki/places/palac/data.json"] = sourcetest.File(`{"id": "palac", "icon": "ic_palac"`)
+	delete(fsys, "stories/01_legenda/content/en/legenda.md")
+	fsys["stories/01_legenda/data.json"] = sourcetest.File(`{"id": "legenda", "markdown_filename": "legenda", "images": ["smok", "smok@256w"]}`)
+
+	report := validate.Check(fsys)
+
+	got := make([]string, 0)
+	for _, problem := range report.Problems {
+		got = append(got, string(problem.Severity)+" "+problem.EntityID+" "+problem.Path)
+	}
+
+	want := []string{
+		"error kosciol sections/01_zabytki/places/kosciol/images/compressed/kosciol_1.webp",
+		"warning kosciol sections/01_zabytki/places/kosciol/content/en/quick_info.txt",
+		"warning kosciol sections/01_zabytki/places/kosciol/content/en/overview.txt",
+		"warning kosciol sections/01_zabytki/places/kosciol/content/en/text_1.txt",
+		"warning kosciol sections/01_zabytki/places/kosciol/content/en/action_1.txt",
+		"error kosciol sections/01_zabytki/places/kosciol/actions.json",
+		"error palac sections/01_zabytki/places/palac/data.json",
+		"error palac sections/01_zabytki/places/palac/content",
+		"warning legenda stories/01_legenda/content/en/legenda.md",
+		"error legenda stories/01_legenda/data.json",
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("got problems:\n%s", cmp.Diff(want, got))
+	}
+
+	if got, want := report.Errors(), 5; got != want {
+		t.Errorf("got %d errors, want %d", got, want)
+	}
+}
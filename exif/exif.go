@@ -0,0 +1,294 @@
+// Package exif reads the few EXIF tags which matter for optimizing photos:
+// orientation and GPS position. Metadata is read from JPEG and HEIC files.
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jdeng/goheif"
+	"github.com/jdeng/goheif/heif"
+)
+
+// ErrNoExif is returned when a file has no EXIF metadata.
+var ErrNoExif = errors.New("no exif metadata")
+
+// Tags read from IFDs.
+const (
+	tagOrientation = 0x0112
+	tagGPSIFD      = 0x8825
+
+	tagGPSLatRef = 0x0001
+	tagGPSLat    = 0x0002
+	tagGPSLngRef = 0x0003
+	tagGPSLng    = 0x0004
+)
+
+// Position is a location where a photo was taken, in decimal degrees.
+type Position struct {
+	Lat float64
+	Lng float64
+}
+
+// Metadata is the EXIF metadata of a photo.
+type Metadata struct {
+	// Orientation is the EXIF orientation between 1 and 8. 1 means the image
+	// is stored upright.
+	Orientation int
+
+	// GPS is the position where the photo was taken, or nil if it's unknown.
+	GPS *Position
+}
+
+// Read reads EXIF metadata of the JPEG or HEIC file at path. Other files have
+// no metadata.
+func Read(path string) (Metadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer file.Close()
+
+	var data []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		data, err = jpegExif(file)
+	case ".heic":
+		data, err = goheif.ExtractExif(file)
+		if errors.Is(err, heif.ErrNoEXIF) {
+			err = ErrNoExif
+		}
+	default:
+		return Metadata{}, ErrNoExif
+	}
+	if err != nil {
+		return Metadata{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	m, err := Parse(data)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// jpegExif returns the payload of the EXIF APP1 segment of a JPEG file.
+func jpegExif(r io.Reader) ([]byte, error) {
+	var soi [2]byte
+	_, err := io.ReadFull(r, soi[:])
+	if err != nil || soi != [2]byte{0xFF, 0xD8} {
+		return nil, errors.New("not a jpeg file")
+	}
+
+	for {
+		var marker [4]byte
+		_, err = io.ReadFull(r, marker[:])
+		if err != nil {
+			return nil, ErrNoExif
+		}
+		if marker[0] != 0xFF {
+			return nil, errors.New("invalid jpeg segment")
+		}
+
+		// Metadata segments precede the image data.
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return nil, ErrNoExif
+		}
+
+		length := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		if length < 0 {
+			return nil, errors.New("invalid jpeg segment")
+		}
+
+		segment := make([]byte, length)
+		_, err = io.ReadFull(r, segment)
+		if err != nil {
+			return nil, fmt.Errorf("read jpeg segment: %w", err)
+		}
+
+		if marker[1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment, nil
+		}
+	}
+}
+
+// Parse parses EXIF metadata stored in TIFF format, optionally preceded by the
+// "Exif\0\0" header.
+func Parse(data []byte) (Metadata, error) {
+	data = bytes.TrimPrefix(data, []byte("Exif\x00\x00"))
+
+	if len(data) < 8 {
+		return Metadata{}, errors.New("invalid tiff header")
+	}
+
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(data, []byte("II*\x00")):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(data, []byte("MM\x00*")):
+		order = binary.BigEndian
+	default:
+		return Metadata{}, errors.New("invalid tiff header")
+	}
+
+	t := tiff{data: data, order: order}
+	m := Metadata{Orientation: 1}
+
+	ifd0, err := t.ifd(order.Uint32(data[4:]))
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	if e, ok := ifd0[tagOrientation]; ok {
+		orientation := int(t.short(e))
+		if orientation >= 1 && orientation <= 8 {
+			m.Orientation = orientation
+		}
+	}
+
+	e, ok := ifd0[tagGPSIFD]
+	if !ok {
+		return m, nil
+	}
+
+	offset := t.long(e)
+	if offset == 0 {
+		return m, nil
+	}
+
+	gps, err := t.ifd(offset)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("gps: %w", err)
+	}
+
+	lat, latOK := t.degrees(gps[tagGPSLat])
+	lng, lngOK := t.degrees(gps[tagGPSLng])
+	if !latOK || !lngOK {
+		return m, nil
+	}
+
+	if t.ascii(gps[tagGPSLatRef]) == "S" {
+		lat = -lat
+	}
+	if t.ascii(gps[tagGPSLngRef]) == "W" {
+		lng = -lng
+	}
+
+	// Cameras without a fix sometimes write zeros.
+	if lat != 0 || lng != 0 {
+		m.GPS = &Position{Lat: lat, Lng: lng}
+	}
+
+	return m, nil
+}
+
+// Types of IFD entries.
+const (
+	typeASCII    = 2
+	typeShort    = 3
+	typeLong     = 4
+	typeRational = 5
+)
+
+var typeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+
+type entry struct {
+	typ   uint16
+	count uint32
+	value []byte // Inline value, or the data it points to.
+}
+
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// ifd returns entries of the IFD at offset, keyed by tag.
+func (t tiff) ifd(offset uint32) (map[uint16]entry, error) {
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return nil, errors.New("ifd out of bounds")
+	}
+
+	n := int(t.order.Uint16(t.data[offset:]))
+	start := int(offset) + 2
+	if start+12*n > len(t.data) {
+		return nil, errors.New("ifd out of bounds")
+	}
+
+	entries := make(map[uint16]entry, n)
+	for i := 0; i < n; i++ {
+		raw := t.data[start+12*i : start+12*i+12]
+		e := entry{typ: t.order.Uint16(raw[2:]), count: t.order.Uint32(raw[4:])}
+
+		size, ok := typeSizes[e.typ]
+		if !ok {
+			continue
+		}
+
+		length := uint64(size) * uint64(e.count)
+		if length <= 4 {
+			e.value = raw[8 : 8+length]
+		} else {
+			valueOffset := uint64(t.order.Uint32(raw[8:]))
+			if valueOffset+length > uint64(len(t.data)) {
+				continue
+			}
+			e.value = t.data[valueOffset : valueOffset+length]
+		}
+
+		entries[t.order.Uint16(raw)] = e
+	}
+
+	return entries, nil
+}
+
+func (t tiff) short(e entry) uint16 {
+	if e.typ != typeShort || len(e.value) < 2 {
+		return 0
+	}
+
+	return t.order.Uint16(e.value)
+}
+
+func (t tiff) long(e entry) uint32 {
+	if e.typ != typeLong || len(e.value) < 4 {
+		return 0
+	}
+
+	return t.order.Uint32(e.value)
+}
+
+func (t tiff) ascii(e entry) string {
+	if e.typ != typeASCII {
+		return ""
+	}
+
+	return strings.TrimRight(string(e.value), "\x00 ")
+}
+
+// degrees converts degrees, minutes and seconds stored as 3 rationals to
+// decimal degrees.
+func (t tiff) degrees(e entry) (float64, bool) {
+	if e.typ != typeRational || e.count != 3 || len(e.value) < 24 {
+		return 0, false
+	}
+
+	var dms [3]float64
+	for i := range dms {
+		num := t.order.Uint32(e.value[8*i:])
+		den := t.order.Uint32(e.value[8*i+4:])
+		if den == 0 {
+			return 0, false
+		}
+		dms[i] = float64(num) / float64(den)
+	}
+
+	return dms[0] + dms[1]/60 + dms[2]/3600, true
+}
@@ -0,0 +1,114 @@
+package exif_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/exif"
+)
+
+// makeTIFF returns big-endian EXIF metadata with orientation and, if lat and
+// lng aren't 0, GPS position with whole minutes in the northern and western
+// hemispheres.
+func makeTIFF(orientation uint16, latDeg, latMin, lngDeg, lngMin uint32) []byte {
+	var b bytes.Buffer
+	w := func(v any) { binary.Write(&b, binary.BigEndian, v) }
+
+	b.WriteString("MM\x00*")
+	w(uint32(8))
+
+	// IFD0 at 8: orientation and GPS IFD pointer.
+	w(uint16(2))
+	w([]uint16{0x0112, 3})
+	w(uint32(1))
+	w(orientation)
+	w(uint16(0))
+	w([]uint16{0x8825, 4})
+	w(uint32(1))
+	w(uint32(38))
+	w(uint32(0))
+
+	// GPS IFD at 38, with rationals after it at 38+2+4*12+4 = 92.
+	w(uint16(4))
+	w([]uint16{1, 2})
+	w(uint32(2))
+	b.WriteString("N\x00\x00\x00")
+	w([]uint16{2, 5})
+	w(uint32(3))
+	w(uint32(92))
+	w([]uint16{3, 2})
+	w(uint32(2))
+	b.WriteString("W\x00\x00\x00")
+	w([]uint16{4, 5})
+	w(uint32(3))
+	w(uint32(116))
+	w(uint32(0))
+
+	w([]uint32{latDeg, 1, latMin, 1, 0, 1})
+	w([]uint32{lngDeg, 1, lngMin, 1, 0, 1})
+
+	return b.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	got, err := exif.Parse(append([]byte("Exif\x00\x00"), makeTIFF(6, 50, 30, 18, 15)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := exif.Metadata{Orientation: 6, GPS: &exif.Position{Lat: 50.5, Lng: -18.25}}
+	if !cmp.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Zeros are written by cameras without a GPS fix.
+	got, err = exif.Parse(makeTIFF(1, 0, 0, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = exif.Metadata{Orientation: 1}
+	if !cmp.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestRead(t *testing.T) {
+	var encoded bytes.Buffer
+	err := jpeg.Encode(&encoded, image.NewGray(image.Rect(0, 0, 4, 4)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert the APP1 segment right after the SOI marker.
+	payload := append([]byte("Exif\x00\x00"), makeTIFF(3, 51, 6, 17, 3)...)
+	segment := append([]byte{0xFF, 0xE1}, binary.BigEndian.AppendUint16(nil, uint16(len(payload)+2))...)
+	data := append(append(append([]byte{}, encoded.Bytes()[:2]...), append(segment, payload...)...), encoded.Bytes()[2:]...)
+
+	dir := t.TempDir()
+	withExif := filepath.Join(dir, "a.jpg")
+	withoutExif := filepath.Join(dir, "b.jpg")
+	os.WriteFile(withExif, data, 0o644)
+	os.WriteFile(withoutExif, encoded.Bytes(), 0o644)
+
+	got, err := exif.Read(withExif)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := exif.Metadata{Orientation: 3, GPS: &exif.Position{Lat: 51.1, Lng: -17.05}}
+	if !cmp.Equal(got, want, cmp.Comparer(func(a, b float64) bool { return a-b < 1e-9 && b-a < 1e-9 })) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	_, err = exif.Read(withoutExif)
+	if err == nil {
+		t.Errorf("got no error for jpeg without exif")
+	}
+}
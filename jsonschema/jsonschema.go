@@ -0,0 +1,215 @@
+// Package jsonschema generates JSON schemas from Go types and validates JSON
+// documents against them.
+//
+// Only the small subset of JSON Schema that is needed to describe a datafile is
+// supported: types, formats, descriptions, object properties, required
+// properties, array items and additional properties.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect that generated schemas conform to.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON schema of a single value.
+type Schema struct {
+	ID                   string
+	Dialect              string
+	Title                string
+	Type                 []string
+	Format               string
+	Description          string
+	Properties           []Property
+	Required             []string
+	Items                *Schema
+	AdditionalProperties *Schema
+}
+
+// Property is a named property of an object. Properties are kept in a slice, so
+// that they're marshalled in the same order as fields of the Go struct.
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// Property returns schema of property name, or nil if there's no such
+// property.
+func (s *Schema) Property(name string) *Schema {
+	for _, property := range s.Properties {
+		if property.Name == name {
+			return property.Schema
+		}
+	}
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	first := true
+	field := func(key string, value any) error {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString(`"` + key + `":`)
+		buf.Write(data)
+		return nil
+	}
+
+	fields := []struct {
+		key   string
+		value any
+		empty bool
+	}{
+		{"$id", s.ID, s.ID == ""},
+		{"$schema", s.Dialect, s.Dialect == ""},
+		{"title", s.Title, s.Title == ""},
+		{"type", s.typeValue(), len(s.Type) == 0},
+		{"format", s.Format, s.Format == ""},
+		{"description", s.Description, s.Description == ""},
+		{"properties", properties(s.Properties), len(s.Properties) == 0},
+		{"required", s.Required, len(s.Required) == 0},
+		{"items", s.Items, s.Items == nil},
+		{"additionalProperties", s.AdditionalProperties, s.AdditionalProperties == nil},
+	}
+
+	for _, f := range fields {
+		if f.empty {
+			continue
+		}
+
+		err := field(f.key, f.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (s *Schema) typeValue() any {
+	if len(s.Type) == 1 {
+		return s.Type[0]
+	}
+
+	return s.Type
+}
+
+type properties []Property
+
+func (p properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, property := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(property.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(property.Schema)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generate creates schema of v's type.
+//
+// Struct fields are described by their "json" tag. Fields without the
+// "omitempty" option are required. Descriptions are read from the
+// "description" tag. Pointers are nullable.
+func Generate(v any) *Schema {
+	return generate(reflect.TypeOf(v))
+}
+
+func generate(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: []string{"string"}, Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := generate(t.Elem())
+		s.Type = append(s.Type, "null")
+		return s
+	case reflect.String:
+		return &Schema{Type: []string{"string"}}
+	case reflect.Bool:
+		return &Schema{Type: []string{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: []string{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: []string{"number"}}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: []string{"array"}, Items: generate(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: []string{"object"}, AdditionalProperties: generate(t.Elem())}
+	case reflect.Struct:
+		return generateStruct(t)
+	}
+
+	// Anything goes.
+	return &Schema{}
+}
+
+func generateStruct(t reflect.Type) *Schema {
+	s := &Schema{Type: []string{"object"}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		property := generate(field.Type)
+		property.Description = field.Tag.Get("description")
+		s.Properties = append(s.Properties, Property{Name: name, Schema: property})
+
+		if !strings.Contains(options, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,101 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentouristics/database-tools/jsonschema"
+)
+
+type point struct {
+	X float32 `json:"x" description:"Horizontal position."`
+	Y float32 `json:"y"`
+}
+
+type shape struct {
+	Name     map[string]string `json:"name"`
+	Points   []point           `json:"points"`
+	Sides    int               `json:"sides"`
+	Created  time.Time         `json:"created"`
+	URL      *string           `json:"url"`
+	Note     string            `json:"note,omitempty"`
+	internal string
+}
+
+func TestGenerate(t *testing.T) {
+	data, err := json.Marshal(jsonschema.Generate(shape{}))
+	if err != nil {
+		t.Fatalf("failed to marshal schema: %v", err)
+	}
+
+	want := `{"type":"object","properties":{` +
+		`"name":{"type":"object","additionalProperties":{"type":"string"}},` +
+		`"points":{"type":"array","items":{"type":"object","properties":{"x":{"type":"number","description":"Horizontal position."},"y":{"type":"number"}},"required":["x","y"]}},` +
+		`"sides":{"type":"integer"},` +
+		`"created":{"type":"string","format":"date-time"},` +
+		`"url":{"type":["string","null"]},` +
+		`"note":{"type":"string"}},` +
+		`"required":["name","points","sides","created","url"]}`
+
+	if string(data) != want {
+		t.Errorf("got schema\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema := jsonschema.Generate(shape{})
+
+	testCases := []struct {
+		name string
+		data string
+		want []string // Substrings of expected errors.
+	}{
+		{
+			name: "valid",
+			data: `{"name": {"pl": "Trójkąt"}, "points": [{"x": 1, "y": 2.5}], "sides": 3, "created": "2021-04-06T22:57:38Z", "url": null}`,
+		},
+		{
+			name: "wrong types",
+			data: `{"name": "Trójkąt", "points": [{"x": "1", "y": 2}], "sides": 3.5, "created": "yesterday", "url": 1}`,
+			want: []string{
+				`/name: got string, want object`,
+				`/points/0/x: got string, want number`,
+				`/sides: got number, want integer`,
+				`/created: "yesterday" is not a valid date-time`,
+				`/url: got number, want string or null`,
+			},
+		},
+		{
+			name: "missing properties",
+			data: `{"name": {}, "points": null}`,
+			want: []string{
+				`/: missing required property "sides"`,
+				`/points: got null, want array`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tc.data))
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("wanted errors, got nil")
+			}
+
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err.Error(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Validate checks that JSON document data conforms to schema s. It returns all
+// violations joined into a single error. Every violation is prefixed with a
+// JSON pointer to the offending value.
+func (s *Schema) Validate(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v any
+	err := decoder.Decode(&v)
+	if err != nil {
+		return fmt.Errorf("decode JSON: %w", err)
+	}
+
+	errs := make([]error, 0)
+	s.validate(v, "", &errs)
+
+	return errors.Join(errs...)
+}
+
+func (s *Schema) validate(v any, pointer string, errs *[]error) {
+	fail := func(format string, args ...any) {
+		location := pointer
+		if location == "" {
+			location = "/"
+		}
+		*errs = append(*errs, fmt.Errorf("%s: %s", location, fmt.Sprintf(format, args...)))
+	}
+
+	got := typeOf(v)
+	if !s.allows(got, v) {
+		fail("got %s, want %s", got, strings.Join(s.Type, " or "))
+		return
+	}
+
+	switch value := v.(type) {
+	case string:
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				fail("%q is not a valid date-time", value)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+
+		for name, property := range value {
+			propertyPointer := pointer + "/" + escapePointer(name)
+			if schema := s.Property(name); schema != nil {
+				schema.validate(property, propertyPointer, errs)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(property, propertyPointer, errs)
+			}
+		}
+	case []any:
+		if s.Items == nil {
+			return
+		}
+
+		for i, item := range value {
+			s.Items.validate(item, fmt.Sprintf("%s/%d", pointer, i), errs)
+		}
+	}
+}
+
+func (s *Schema) allows(got string, v any) bool {
+	if len(s.Type) == 0 {
+		return true
+	}
+
+	for _, want := range s.Type {
+		if want == got {
+			return true
+		}
+
+		if want == "integer" && got == "number" && isInteger(v.(json.Number)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+func isInteger(n json.Number) bool {
+	_, err := n.Int64()
+	return err == nil
+}
+
+func escapePointer(name string) string {
+	name = strings.ReplaceAll(name, "~", "~0")
+	return strings.ReplaceAll(name, "/", "~1")
+}
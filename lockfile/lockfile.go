@@ -0,0 +1,98 @@
+// Package lockfile implements simple advisory locks based on files. They
+// prevent running two commands that modify the same region's files at once.
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrLocked is returned when the lock is already held by someone else.
+var ErrLocked = errors.New("locked")
+
+// Lock is a held lock. It must be released when it's not needed anymore.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire takes the lock represented by file at path. The file contains PID
+// of the process holding the lock.
+//
+// The lock is an OS advisory lock on the file, so it is released by the
+// kernel when the process exits, even if it crashes or gets killed. The file
+// itself is left in place and reused by later calls.
+//
+// If the lock is held by another process, Acquire fails with an error
+// wrapping ErrLocked.
+func Acquire(path string) (*Lock, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("make dir for lock file: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+
+	err = lock(file)
+	if errors.Is(err, ErrLocked) {
+		holder := "unknown process"
+		if data, err := io.ReadAll(file); err == nil && len(data) > 0 {
+			holder = "process " + strings.TrimSpace(string(data))
+		}
+		file.Close()
+
+		return nil, fmt.Errorf("%w by %s", ErrLocked, holder)
+	} else if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("lock file: %w", err)
+	}
+
+	err = file.Truncate(0)
+	if err == nil {
+		_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	if err != nil {
+		unlock(file)
+		file.Close()
+		return nil, fmt.Errorf("write lock file: %w", err)
+	}
+
+	return &Lock{file: file}, nil
+}
+
+// AcquireRegion takes the lock of region's generated and compressed files.
+func AcquireRegion(regionID string) (*Lock, error) {
+	lock, err := Acquire(filepath.Join("generated", ".locks", regionID+".lock"))
+	if err != nil {
+		return nil, fmt.Errorf("region %s is %w", regionID, err)
+	}
+
+	return lock, nil
+}
+
+// Release gives the lock back.
+//
+// The lock file is not removed, because another process may already have it
+// open and be waiting to lock it.
+func (l *Lock) Release() error {
+	defer l.file.Close()
+
+	err := l.file.Truncate(0)
+	if err != nil {
+		return fmt.Errorf("truncate lock file: %w", err)
+	}
+
+	err = unlock(l.file)
+	if err != nil {
+		return fmt.Errorf("unlock file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package lockfile_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/opentouristics/database-tools/lockfile"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "rudy.lock")
+
+	lock, err := lockfile.Acquire(path)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	_, err = lockfile.Acquire(path)
+	if !errors.Is(err, lockfile.ErrLocked) {
+		t.Fatalf("got error %v, want %v", err, lockfile.ErrLocked)
+	}
+
+	err = lock.Release()
+	if err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+
+	lock, err = lockfile.Acquire(path)
+	if err != nil {
+		t.Fatalf("failed to acquire released lock: %v", err)
+	}
+	lock.Release()
+}
+
+func TestAcquireLeftover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rudy.lock")
+
+	// A lock file left behind by a process that crashed.
+	err := os.WriteFile(path, []byte("999999\n"), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write lock file: %v", err)
+	}
+
+	lock, err := lockfile.Acquire(path)
+	if err != nil {
+		t.Fatalf("failed to acquire lock left by a dead process: %v", err)
+	}
+	defer lock.Release()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read lock file: %v", err)
+	}
+
+	want := strconv.Itoa(os.Getpid()) + "\n"
+	if string(data) != want {
+		t.Errorf("got lock file contents %q, want %q", data, want)
+	}
+}
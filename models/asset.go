@@ -0,0 +1,75 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+// Directories of the generated datafile in which assets are stored.
+const (
+	ImagesDir  = "images"
+	StoriesDir = "stories"
+)
+
+// Asset is a file referenced by the datafile that has to be shipped together
+// with data.json.
+type Asset struct {
+	Owner string // Entity referencing the asset, e.g "place kosciol".
+	Path  string // Path relative to the root of the datafile's file system.
+	Dir   string // Directory in the generated datafile, ImagesDir or StoriesDir.
+	Name  string // Name of the file in Dir.
+}
+
+func imageAssets(owner string, paths []string) []Asset {
+	assets := make([]Asset, 0, len(paths))
+	for _, imagePath := range paths {
+		assets = append(assets, Asset{Owner: owner, Path: imagePath, Dir: ImagesDir, Name: path.Base(imagePath)})
+	}
+
+	return assets
+}
+
+// Assets returns all files referenced by the datafile, in a deterministic
+// order. It returns an error if two different files would end up under the
+// same name in the generated datafile.
+func (d *Datafile) Assets() ([]Asset, error) {
+	all := make([]Asset, 0)
+	for _, section := range d.Sections {
+		all = append(all, section.Assets()...)
+		for _, place := range section.Places {
+			all = append(all, place.Assets()...)
+		}
+	}
+
+	for _, track := range d.Tracks {
+		all = append(all, track.Assets()...)
+	}
+
+	for _, story := range d.Stories {
+		all = append(all, story.Assets()...)
+	}
+
+	assets := make([]Asset, 0, len(all))
+	errs := make([]error, 0)
+	byDst := make(map[string]Asset)
+	for _, asset := range all {
+		dst := path.Join(asset.Dir, asset.Name)
+		other, ok := byDst[dst]
+		if !ok {
+			byDst[dst] = asset
+			assets = append(assets, asset)
+			continue
+		}
+
+		// The same file referenced many times is fine.
+		if other.Path != asset.Path {
+			errs = append(errs, fmt.Errorf(
+				"%s of %s and %s of %s would both be written to %s",
+				other.Path, other.Owner, asset.Path, asset.Owner, dst,
+			))
+		}
+	}
+
+	return assets, errors.Join(errs...)
+}
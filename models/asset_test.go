@@ -0,0 +1,82 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/sourcetest"
+)
+
+func TestAssets(t *testing.T) {
+	t.Run("all referenced files", func(t *testing.T) {
+		fsys := sourcetest.Datafile()
+		fsys["sections/01_zabytki/data.json"] = sourcetest.File(`{"id": "zabytki", "background_image": "bg_zabytki"}`)
+		fsys["sections/01_zabytki/images/compressed/bg_zabytki.webp"] = sourcetest.File("")
+		fsys["tracks/01_szlak/data.json"] = sourcetest.File(`{"id": "szlak", "images": ["szlak_1"]}`)
+		fsys["tracks/01_szlak/content/pl/name.txt"] = sourcetest.File("Szlak\n")
+		fsys["tracks/01_szlak/content/pl/overview.txt"] = sourcetest.File("Długi\n")
+		fsys["tracks/01_szlak/content/pl/quick_info.txt"] = sourcetest.File("Rowerowy\n")
+		fsys["tracks/01_szlak/images/compressed/szlak_1.webp"] = sourcetest.File("")
+
+		datafile, err := models.ParseDatafile(fsys, models.Compressed, 1, false)
+		if err != nil {
+			t.Fatalf("failed to parse datafile: %v", err)
+		}
+
+		assets, err := datafile.Assets()
+		if err != nil {
+			t.Fatalf("failed to collect assets: %v", err)
+		}
+
+		got := make([]string, 0)
+		for _, asset := range assets {
+			got = append(got, asset.Owner+": "+asset.Dir+"/"+asset.Name)
+		}
+
+		want := []string{
+			"section zabytki: images/bg_zabytki.webp",
+			"place kosciol: images/kosciol_1.webp",
+			"place kosciol: images/ic_kosciol.webp",
+			"track szlak: images/szlak_1.webp",
+			"story legenda: images/smok.webp",
+			"story legenda: stories/legenda.md",
+			"story legenda: stories/legenda.en.md",
+			"story legenda: stories/legenda.pl.md",
+		}
+
+		if !cmp.Equal(got, want) {
+			t.Errorf("got assets (-want +got):\n%s", cmp.Diff(want, got))
+		}
+	})
+
+	t.Run("missing track image", func(t *testing.T) {
+		fsys := sourcetest.Datafile()
+		fsys["tracks/01_szlak/data.json"] = sourcetest.File(`{"id": "szlak", "images": ["szlak_1"]}`)
+		fsys["tracks/01_szlak/content/pl/name.txt"] = sourcetest.File("Szlak\n")
+		fsys["tracks/01_szlak/content/pl/overview.txt"] = sourcetest.File("Długi\n")
+		fsys["tracks/01_szlak/content/pl/quick_info.txt"] = sourcetest.File("Rowerowy\n")
+
+		_, err := models.ParseDatafile(fsys, models.Compressed, 1, false)
+		if err == nil {
+			t.Error("wanted error, got nil")
+		}
+	})
+
+	t.Run("colliding names", func(t *testing.T) {
+		fsys := sourcetest.Datafile()
+		fsys["stories/01_legenda/data.json"] = sourcetest.File(`{"id": "legenda", "markdown_filename": "legenda", "images": ["kosciol_1"]}`)
+		fsys["stories/01_legenda/images/compressed/kosciol_1.webp"] = sourcetest.WebP(8, 6)
+
+		datafile, err := models.ParseDatafile(fsys, models.Compressed, 1, false)
+		if err != nil {
+			t.Fatalf("failed to parse datafile: %v", err)
+		}
+
+		_, err = datafile.Assets()
+		if err == nil || !strings.Contains(err.Error(), "images/kosciol_1.webp") {
+			t.Errorf("got error %v, want collision at images/kosciol_1.webp", err)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package models
+
+// Changelog lists places which changed since the previous version of the
+// datafile.
+type Changelog struct {
+	Since         string   `json:"since" description:"Version of the datafile that the changes are relative to: its commit tag, commit hash or git revision."`
+	NewPlaces     []string `json:"new_places" description:"IDs of places that were added."`
+	UpdatedPlaces []string `json:"updated_places" description:"IDs of places whose data changed."`
+	RemovedPlaces []string `json:"removed_places" description:"IDs of places that were removed."`
+}
+
+// NewChangelog makes a changelog out of changes (see Diff) since the version
+// since.
+func NewChangelog(since string, changes []Change) *Changelog {
+	changelog := Changelog{
+		Since:         since,
+		NewPlaces:     make([]string, 0),
+		UpdatedPlaces: make([]string, 0),
+		RemovedPlaces: make([]string, 0),
+	}
+
+	for _, change := range changes {
+		if change.Entity != "place" {
+			continue
+		}
+
+		switch change.Kind {
+		case Added:
+			changelog.NewPlaces = append(changelog.NewPlaces, change.ID)
+		case Modified:
+			changelog.UpdatedPlaces = append(changelog.UpdatedPlaces, change.ID)
+		case Removed:
+			changelog.RemovedPlaces = append(changelog.RemovedPlaces, change.ID)
+		}
+	}
+
+	return &changelog
+}
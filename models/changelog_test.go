@@ -0,0 +1,30 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/models"
+)
+
+func TestNewChangelog(t *testing.T) {
+	changes := []models.Change{
+		{Entity: "section", ID: "zabytki", Kind: models.Modified},
+		{Entity: "place", ID: "kosciol", Kind: models.Modified},
+		{Entity: "place", ID: "mlyn", Kind: models.Removed},
+		{Entity: "place", ID: "zamek", Kind: models.Added},
+		{Entity: "story", ID: "legenda", Kind: models.Added},
+	}
+
+	want := &models.Changelog{
+		Since:         "v1.0.0",
+		NewPlaces:     []string{"zamek"},
+		UpdatedPlaces: []string{"kosciol"},
+		RemovedPlaces: []string{"mlyn"},
+	}
+
+	got := models.NewChangelog("v1.0.0", changes)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("changelog mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -2,3 +2,15 @@ package models
 
 // Text maps language code to text.
 type Text map[string]string
+
+// Localized returns the text in the first of langs in which it is available,
+// or an empty string if there's none.
+func (t Text) Localized(langs ...string) string {
+	for _, lang := range langs {
+		if value, ok := t[lang]; ok {
+			return value
+		}
+	}
+
+	return ""
+}
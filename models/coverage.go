@@ -0,0 +1,204 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// CoverageCell is a single text of an entity in a single language.
+type CoverageCell struct {
+	Group    string `json:"group"`     // ID of the section directory, or "meta", "tracks" or "stories".
+	Entity   string `json:"entity"`    // Type of the entity, e.g "place".
+	EntityID string `json:"entity_id"` // Name of the entity's directory.
+	Field    string `json:"field"`     // Name of the text file, e.g "quick_info.txt".
+	Lang     string `json:"lang"`
+	Path     string `json:"path"` // Path to the file, relative to the datafile's root.
+	Present  bool   `json:"present"`
+}
+
+// Coverage is a matrix of texts of all entities in all languages of a
+// datafile.
+type Coverage struct {
+	Languages []string
+	Groups    []string
+	Cells     []CoverageCell
+}
+
+// Percent returns percentage of texts present in lang. If group is not empty,
+// only texts in that group are counted.
+func (c *Coverage) Percent(lang string, group string) float64 {
+	total, present := 0, 0
+	for _, cell := range c.Cells {
+		if cell.Lang != lang || (group != "" && cell.Group != group) {
+			continue
+		}
+
+		total++
+		if cell.Present {
+			present++
+		}
+	}
+
+	if total == 0 {
+		return 100
+	}
+
+	return 100 * float64(present) / float64(total)
+}
+
+// Required returns percentage of all texts present in lang, for checking it
+// against a threshold. Unlike Percent, it returns 0 if lang has no texts at
+// all, e.g when the datafile has no content in lang.
+func (c *Coverage) Required(lang string) float64 {
+	for _, cell := range c.Cells {
+		if cell.Lang == lang {
+			return c.Percent(lang, "")
+		}
+	}
+
+	return 0
+}
+
+// Missing returns cells of texts that are missing.
+func (c *Coverage) Missing() []CoverageCell {
+	missing := make([]CoverageCell, 0)
+	for _, cell := range c.Cells {
+		if !cell.Present {
+			missing = append(missing, cell)
+		}
+	}
+
+	return missing
+}
+
+// Measure builds the coverage matrix of the datafile. fsys must be rooted at
+// the datafile's directory.
+//
+// Texts of an entity are name.txt, quick_info.txt and overview.txt (those which
+// the entity has), and text_* and action_* files present in any of its
+// languages. Languages are those present in any entity of the datafile.
+func Measure(fsys fs.FS) (Coverage, error) {
+	var c Coverage
+
+	entities := []textEntity{{group: "meta", kind: "meta", dir: "meta", fields: []string{"name.txt"}}}
+
+	sectionDirs, err := subdirs(fsys, "sections")
+	if err != nil {
+		return c, fmt.Errorf("read sections: %w", err)
+	}
+
+	for _, sectionDir := range sectionDirs {
+		group := path.Base(sectionDir)
+		entities = append(entities, textEntity{group: group, kind: "section", dir: sectionDir, fields: []string{"name.txt", "quick_info.txt"}})
+
+		placeDirs, err := subdirs(fsys, path.Join(sectionDir, "places"))
+		if err != nil {
+			return c, fmt.Errorf("read places of section %s: %w", group, err)
+		}
+
+		for _, placeDir := range placeDirs {
+			entities = append(entities, textEntity{group: group, kind: "place", dir: placeDir, fields: []string{"name.txt", "quick_info.txt", "overview.txt"}, prefixes: []string{"text_", "action_"}})
+		}
+	}
+
+	for _, group := range []struct{ dir, kind string }{{"tracks", "track"}, {"stories", "story"}} {
+		dirs, err := subdirs(fsys, group.dir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return c, fmt.Errorf("read %s: %w", group.dir, err)
+		}
+
+		fields := []string{"name.txt", "quick_info.txt", "overview.txt"}
+		if group.kind == "story" {
+			fields = []string{"name.txt"}
+		}
+
+		for _, dir := range dirs {
+			entities = append(entities, textEntity{group: group.dir, kind: group.kind, dir: dir, fields: fields})
+		}
+	}
+
+	seenLangs := make(map[string]bool)
+	for i := range entities {
+		langDirs, _ := subdirs(fsys, path.Join(entities[i].dir, "content"))
+		for _, langDir := range langDirs {
+			lang := path.Base(langDir)
+			entities[i].langs = append(entities[i].langs, lang)
+			seenLangs[lang] = true
+		}
+	}
+
+	for lang := range seenLangs {
+		c.Languages = append(c.Languages, lang)
+	}
+	sort.Strings(c.Languages)
+
+	seenGroups := make(map[string]bool)
+	for _, e := range entities {
+		if !seenGroups[e.group] {
+			seenGroups[e.group] = true
+			c.Groups = append(c.Groups, e.group)
+		}
+
+		for _, field := range e.allFields(fsys) {
+			for _, lang := range c.Languages {
+				filePath := path.Join(e.dir, "content", lang, field)
+				_, err := fs.Stat(fsys, filePath)
+
+				c.Cells = append(c.Cells, CoverageCell{
+					Group:    e.group,
+					Entity:   e.kind,
+					EntityID: path.Base(e.dir),
+					Field:    field,
+					Lang:     lang,
+					Path:     filePath,
+					Present:  err == nil,
+				})
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// textEntity is a single meta, section, place, track or story whose texts are
+// measured.
+type textEntity struct {
+	group    string
+	kind     string
+	dir      string
+	fields   []string
+	prefixes []string
+	langs    []string // Languages present in the entity's content directory.
+}
+
+// allFields returns fields of entity e and names of files with e's prefixes
+// present in any of its languages, sorted.
+func (e *textEntity) allFields(fsys fs.FS) []string {
+	seen := make(map[string]bool)
+	for _, lang := range e.langs {
+		entries, err := fs.ReadDir(fsys, path.Join(e.dir, "content", lang))
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			for _, prefix := range e.prefixes {
+				if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+					seen[entry.Name()] = true
+				}
+			}
+		}
+	}
+
+	prefixed := make([]string, 0, len(seen))
+	for field := range seen {
+		prefixed = append(prefixed, field)
+	}
+	sort.Strings(prefixed)
+
+	return append(append([]string{}, e.fields...), prefixed...)
+}
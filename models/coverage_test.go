@@ -0,0 +1,66 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/sourcetest"
+)
+
+func TestMeasure(t *testing.T) {
+	fsys := sourcetest.Datafile()
+	fsys["sections/01_zabytki/content/en/name.txt"] = sourcetest.File("Monuments\n")
+	fsys["sections/01_zabytki/places/kosciol/content/en/quick_info.txt"] = sourcetest.File("Gothic\n")
+	fsys["sections/01_zabytki/places/kosciol/content/en/overview.txt"] = sourcetest.File("Very\nold\n")
+
+	c, err := models.Measure(fsys)
+	if err != nil {
+		t.Fatalf("failed to measure coverage: %v", err)
+	}
+
+	if want := []string{"en", "pl"}; !cmp.Equal(c.Languages, want) {
+		t.Errorf("got languages %q, want %q", c.Languages, want)
+	}
+
+	if want := []string{"meta", "01_zabytki", "stories"}; !cmp.Equal(c.Groups, want) {
+		t.Errorf("got groups %q, want %q", c.Groups, want)
+	}
+
+	got := make([]string, 0)
+	for _, cell := range c.Missing() {
+		got = append(got, cell.Path)
+	}
+
+	want := []string{
+		"sections/01_zabytki/content/en/quick_info.txt",
+		"sections/01_zabytki/places/kosciol/content/en/action_1.txt",
+		"sections/01_zabytki/places/kosciol/content/en/text_1.txt",
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("got missing files:\n%s", cmp.Diff(want, got))
+	}
+
+	if got, want := c.Percent("pl", ""), 100.0; got != want {
+		t.Errorf("got pl coverage %.1f%%, want %.1f%%", got, want)
+	}
+
+	if got, want := c.Percent("en", "01_zabytki"), 100*4/7.0; got != want {
+		t.Errorf("got en coverage of section %.1f%%, want %.1f%%", got, want)
+	}
+}
+
+func TestRequired(t *testing.T) {
+	c, err := models.Measure(sourcetest.Datafile())
+	if err != nil {
+		t.Fatalf("failed to measure coverage: %v", err)
+	}
+
+	if got, want := c.Required("en"), 100*3/9.0; got != want {
+		t.Errorf("got en coverage %.1f%%, want %.1f%%", got, want)
+	}
+
+	if got, want := c.Required("de"), 0.0; got != want {
+		t.Errorf("got coverage of missing de %.1f%%, want %.1f%%", got, want)
+	}
+}
@@ -1,12 +1,105 @@
 // Package models defines structure of a datafile.
 package models
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/opentouristics/database-tools/parallel"
+)
+
 // Datafile represents structure of data.json file.
 type Datafile struct {
-	Meta     Meta      `json:"meta"`
-	Sections []Section `json:"sections"`
-	Tracks   []Track   `json:"tracks"`
-	Stories  []Story   `json:"stories"`
+	Meta     Meta      `json:"meta" description:"Metadata of the datafile."`
+	Sections []Section `json:"sections" description:"Sections in the datafile."`
+	Tracks   []Track   `json:"tracks" description:"Bike trails in the datafile."`
+	Stories  []Story   `json:"stories" description:"Longer texts about particular topics."`
+	policy   LanguagePolicy
+}
+
+// ParseDatafile parses the source of a datafile. fsys must be rooted at the
+// datafile's directory, e.g datafiles/datafile-rudy.
+//
+// Paths of images in quality are collected. Places, tracks and stories are
+// parsed by at most jobs goroutines at once.
+//
+// Fields that depend on the environment (generation time, commit hash and tag)
+// are left empty.
+//
+// Texts must be available in languages required by the datafile's language
+// policy (see ReadLanguagePolicy).
+func ParseDatafile(fsys fs.FS, quality Quality, jobs int, verbose bool) (Datafile, error) {
+	var datafile Datafile
+
+	policy, err := ReadLanguagePolicy(fsys)
+	if err != nil {
+		return datafile, fmt.Errorf("read language policy: %w", err)
+	}
+	datafile.policy = policy
+
+	err = datafile.Meta.Parse(fsys, "meta")
+	if err != nil {
+		return datafile, fmt.Errorf("parse meta: %w", err)
+	}
+
+	sections, err := parseSections(fsys, quality, jobs, verbose)
+	if err != nil {
+		return datafile, fmt.Errorf("parse sections: %w", err)
+	}
+	datafile.Sections = sections
+	datafile.Meta.PlaceCount = len(datafile.AllPlaces())
+
+	tracks, err := parseTracks(fsys, quality, jobs)
+	if err != nil {
+		return datafile, fmt.Errorf("parse tracks: %w", err)
+	}
+	datafile.Tracks = tracks
+
+	stories, err := parseStories(fsys, quality, jobs)
+	if err != nil {
+		return datafile, fmt.Errorf("parse stories: %w", err)
+	}
+	datafile.Stories = stories
+
+	datafile.Meta.Languages = datafile.Languages()
+
+	err = policy.Check(&datafile)
+	if err != nil {
+		return datafile, fmt.Errorf("texts violate language policy:\n%w", err)
+	}
+
+	return datafile, nil
+}
+
+// Policy returns the language policy of the datafile. Datafiles which weren't
+// parsed from their source have the default policy.
+func (d *Datafile) Policy() LanguagePolicy {
+	if d.policy.Primary == "" {
+		return DefaultLanguagePolicy()
+	}
+
+	return d.policy
+}
+
+// ParseGeneratedDatafile parses a datafile that was already generated. It
+// looks for data.json in the root of fsys.
+func ParseGeneratedDatafile(fsys fs.FS) (Datafile, error) {
+	var datafile Datafile
+
+	data, err := fs.ReadFile(fsys, "data.json")
+	if err != nil {
+		return datafile, err
+	}
+
+	err = json.Unmarshal(data, &datafile)
+	if err != nil {
+		return datafile, fmt.Errorf("unmarshal data.json: %w", err)
+	}
+
+	return datafile, nil
 }
 
 // AllPlaces returns places from all sections.
@@ -18,3 +111,88 @@ func (d *Datafile) AllPlaces() []Place {
 
 	return places
 }
+
+func parseSections(fsys fs.FS, quality Quality, jobs int, verbose bool) ([]Section, error) {
+	sections := make([]Section, 0)
+
+	dirs, err := subdirs(fsys, "sections")
+	if err != nil {
+		return sections, fmt.Errorf("read sections: %w", err)
+	}
+
+	// Sections are parsed one by one, but places inside of them in parallel.
+	errs := make([]error, 0)
+	for _, dir := range dirs {
+		var section Section
+		err = section.Parse(fsys, dir, quality, jobs, verbose)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("parse %s: %w", dir, err))
+			continue
+		}
+
+		sections = append(sections, section)
+	}
+
+	return sections, errors.Join(errs...)
+}
+
+func parseTracks(fsys fs.FS, quality Quality, jobs int) ([]Track, error) {
+	dirs, err := subdirs(fsys, "tracks")
+	if errors.Is(err, fs.ErrNotExist) {
+		return make([]Track, 0), nil
+	} else if err != nil {
+		return make([]Track, 0), fmt.Errorf("read tracks: %w", err)
+	}
+
+	tracks := make([]Track, len(dirs))
+	err = parallel.ForEach(len(dirs), jobs, func(i int) error {
+		err := tracks[i].Parse(fsys, dirs[i], quality)
+		if err != nil {
+			return fmt.Errorf("parse track %s: %w", dirs[i], err)
+		}
+
+		return nil
+	})
+
+	return tracks, err
+}
+
+func parseStories(fsys fs.FS, quality Quality, jobs int) ([]Story, error) {
+	dirs, err := subdirs(fsys, "stories")
+	if errors.Is(err, fs.ErrNotExist) {
+		return make([]Story, 0), nil
+	} else if err != nil {
+		return make([]Story, 0), fmt.Errorf("read stories: %w", err)
+	}
+
+	stories := make([]Story, len(dirs))
+	err = parallel.ForEach(len(dirs), jobs, func(i int) error {
+		err := stories[i].Parse(fsys, dirs[i], quality)
+		if err != nil {
+			return fmt.Errorf("parse story %s: %w", dirs[i], err)
+		}
+
+		return nil
+	})
+
+	return stories, err
+}
+
+// subdirs returns paths of directories directly inside dir, in lexical order.
+func subdirs(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		paths = append(paths, path.Join(dir, entry.Name()))
+	}
+
+	return paths, nil
+}
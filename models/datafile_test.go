@@ -0,0 +1,171 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/sourcetest"
+)
+
+func TestParseDatafile(t *testing.T) {
+	datafile, err := models.ParseDatafile(sourcetest.Datafile(), models.Compressed, 1, false)
+	if err != nil {
+		t.Fatalf("failed to parse datafile: %v", err)
+	}
+
+	if got, want := datafile.Meta.RegionName, (models.Text{"pl": "Rudy", "en": "Rudy"}); !cmp.Equal(got, want) {
+		t.Errorf("got region name %q, want %q", got, want)
+	}
+
+	if got, want := datafile.Meta.PlaceCount, 1; got != want {
+		t.Errorf("got place count %d, want %d", got, want)
+	}
+
+	if got, want := len(datafile.Sections), 1; got != want {
+		t.Fatalf("got %d sections, want %d", got, want)
+	}
+
+	section := datafile.Sections[0]
+	if got, want := section.QuickInfo, (models.Text{"pl": "Stare budynki"}); !cmp.Equal(got, want) {
+		t.Errorf("got section quick info %q, want %q", got, want)
+	}
+
+	place := section.Places[0]
+	if got, want := place.Name, (models.Text{"pl": "Kościół", "en": "Church"}); !cmp.Equal(got, want) {
+		t.Errorf("got place name %q, want %q", got, want)
+	}
+
+	if got, want := place.Content, []models.Text{{"pl": "Zbudowany dawno"}}; !cmp.Equal(got, want) {
+		t.Errorf("got place content %q, want %q", got, want)
+	}
+
+	wantActions := []models.Action{{Name: models.Text{"pl": "Strona"}, Value: "https://example.com"}}
+	if !cmp.Equal(place.Actions, wantActions) {
+		t.Errorf("got place actions %v, want %v", place.Actions, wantActions)
+	}
+
+	wantImagePaths := []string{
+		"sections/01_zabytki/places/kosciol/images/compressed/kosciol_1.webp",
+		"sections/01_zabytki/places/kosciol/images/compressed/ic_kosciol.webp",
+	}
+	if !cmp.Equal(place.ImagePaths(), wantImagePaths) {
+		t.Errorf("got image paths %q, want %q", place.ImagePaths(), wantImagePaths)
+	}
+
+	if got, want := len(datafile.Tracks), 0; got != want {
+		t.Errorf("got %d tracks, want %d", got, want)
+	}
+
+	story := datafile.Stories[0]
+	if got, want := story.MarkdownPath(), "stories/01_legenda/content/pl/legenda.md"; got != want {
+		t.Errorf("got markdown path %q, want %q", got, want)
+	}
+
+	wantMarkdownFiles := map[string]string{"pl": "legenda.pl.md", "en": "legenda.en.md"}
+	if !cmp.Equal(story.MarkdownFiles, wantMarkdownFiles) {
+		t.Errorf("got markdown files %q, want %q", story.MarkdownFiles, wantMarkdownFiles)
+	}
+
+	data, err := json.Marshal(datafile)
+	if err != nil {
+		t.Fatalf("failed to marshal datafile: %v", err)
+	}
+
+	err = models.DatafileSchema().Validate(data)
+	if err != nil {
+		t.Errorf("parsed datafile does not conform to the schema: %v", err)
+	}
+}
+
+func TestParseDatafileImages(t *testing.T) {
+	fsys := sourcetest.Datafile()
+	fsys["sections/01_zabytki/places/kosciol/images/compressed/kosciol_1@256w.webp"] = sourcetest.WebP(4, 3)
+
+	datafile, err := models.ParseDatafile(fsys, models.Compressed, 1, false)
+	if err != nil {
+		t.Fatalf("failed to parse datafile: %v", err)
+	}
+
+	// Parsing doesn't decode images.
+	parsed := datafile.AllPlaces()[0].Images
+	if want := []models.Image{{Name: "kosciol_1", Variants: []models.ImageVariant{}}}; !cmp.Equal(parsed, want) {
+		t.Errorf("got parsed images %+v, want %+v", parsed, want)
+	}
+
+	err = datafile.DescribeImages(fsys, 1)
+	if err != nil {
+		t.Fatalf("failed to describe images: %v", err)
+	}
+
+	place := datafile.AllPlaces()[0]
+	if len(place.Images) != 1 || place.Images[0].Blurhash == "" {
+		t.Fatalf("got images %+v, want one with blurhash", place.Images)
+	}
+
+	got := place.Images[0]
+	got.Blurhash = ""
+	want := models.Image{Name: "kosciol_1", Width: 8, Height: 6, Variants: []models.ImageVariant{{Name: "kosciol_1@256w", Width: 4, Height: 3}}}
+	if !cmp.Equal(got, want) {
+		t.Errorf("got image %+v, want %+v", got, want)
+	}
+
+	wantImagePaths := []string{
+		"sections/01_zabytki/places/kosciol/images/compressed/kosciol_1.webp",
+		"sections/01_zabytki/places/kosciol/images/compressed/ic_kosciol.webp",
+		"sections/01_zabytki/places/kosciol/images/compressed/kosciol_1@256w.webp",
+	}
+	if !cmp.Equal(place.ImagePaths(), wantImagePaths) {
+		t.Errorf("got image paths %q, want %q", place.ImagePaths(), wantImagePaths)
+	}
+
+	var source models.Place
+	err = json.Unmarshal([]byte(`{"images": ["kosciol_1"]}`), &source)
+	if err != nil || !cmp.Equal(source.Images, []models.Image{{Name: "kosciol_1"}}) {
+		t.Errorf("got images %+v (error %v) from names in the source", source.Images, err)
+	}
+}
+
+func TestParseDatafileMissingImage(t *testing.T) {
+	fsys := sourcetest.Datafile()
+	delete(fsys, "sections/01_zabytki/places/kosciol/images/compressed/kosciol_1.webp")
+
+	_, err := models.ParseDatafile(fsys, models.Compressed, 1, false)
+	if err == nil {
+		t.Error("wanted error, got nil")
+	}
+}
+
+func TestParseDatafileOriginalQuality(t *testing.T) {
+	fsys := sourcetest.Datafile()
+	datafile, err := models.ParseDatafile(fsys, models.Original, 1, false)
+	if err != nil {
+		t.Fatalf("failed to parse datafile: %v", err)
+	}
+
+	err = datafile.DescribeImages(fsys, 1)
+	if err != nil {
+		t.Fatalf("failed to describe images: %v", err)
+	}
+
+	wantImagePaths := []string{
+		"sections/01_zabytki/places/kosciol/images/full/kosciol_1.webp",
+		"sections/01_zabytki/places/kosciol/images/full/ic_kosciol.webp",
+	}
+	if got := datafile.AllPlaces()[0].ImagePaths(); !cmp.Equal(got, wantImagePaths) {
+		t.Errorf("got image paths %q, want %q", got, wantImagePaths)
+	}
+
+	wantStoryImagePaths := []string{"stories/01_legenda/images/full/smok.webp"}
+	if got := datafile.Stories[0].ImagePaths(); !cmp.Equal(got, wantStoryImagePaths) {
+		t.Errorf("got story image paths %q, want %q", got, wantStoryImagePaths)
+	}
+
+	// Full-resolution images have no variants, and their blurhash comes from
+	// the compressed image.
+	got := datafile.Stories[0].Images[0]
+	if got.Width != 24 || got.Height != 32 || len(got.Variants) != 0 || got.Blurhash == "" {
+		t.Errorf("got story image %+v, want 24x32 without variants, with blurhash", got)
+	}
+}
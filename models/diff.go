@@ -0,0 +1,269 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind tells what happened to an entity between two datafiles.
+type ChangeKind string
+
+// Kinds of changes.
+const (
+	Added    ChangeKind = "added"
+	Removed  ChangeKind = "removed"
+	Modified ChangeKind = "modified"
+)
+
+// FieldChange is a change of a single field of an entity. Texts are compared
+// per language, so every changed language gets its own FieldChange.
+type FieldChange struct {
+	Field string `json:"field"`          // JSON name of the field, e.g "name" or "content[2]".
+	Lang  string `json:"lang,omitempty"` // Language of the text, empty if the field isn't a text.
+	Old   string `json:"old"`            // Old value. Values other than strings are encoded as JSON.
+	New   string `json:"new"`            // New value. Values other than strings are encoded as JSON.
+}
+
+// Change describes what happened to a single section, place, track or story.
+type Change struct {
+	Entity        string        `json:"entity"` // Type of the entity, e.g "place".
+	ID            string        `json:"id"`
+	Kind          ChangeKind    `json:"kind"`
+	Fields        []FieldChange `json:"fields,omitempty"`
+	ImagesAdded   []string      `json:"images_added,omitempty"`
+	ImagesRemoved []string      `json:"images_removed,omitempty"`
+}
+
+// Diff compares datafile old with datafile new and returns changes of their
+// sections, places, tracks and stories, in this order. Places are reported
+// separately from the sections they belong to.
+func Diff(old, new *Datafile) []Change {
+	changes := make([]Change, 0)
+
+	changes = appendChanges(changes, "section", old.Sections, new.Sections, func(s Section) string { return s.ID })
+	changes = appendChanges(changes, "place", old.AllPlaces(), new.AllPlaces(), func(p Place) string { return p.ID })
+	changes = appendChanges(changes, "track", old.Tracks, new.Tracks, func(t Track) string { return t.ID })
+	changes = appendChanges(changes, "story", old.Stories, new.Stories, func(s Story) string { return s.ID })
+
+	return changes
+}
+
+// appendChanges matches entities from old and new by ID and appends their
+// changes to changes. Removed and modified entities come in the old order,
+// followed by added entities in the new order.
+func appendChanges[T any](changes []Change, entity string, old, new []T, id func(T) string) []Change {
+	newByID := make(map[string]T, len(new))
+	for _, v := range new {
+		newByID[id(v)] = v
+	}
+
+	oldIDs := make(map[string]bool, len(old))
+	for _, oldValue := range old {
+		oldIDs[id(oldValue)] = true
+
+		newValue, ok := newByID[id(oldValue)]
+		if !ok {
+			changes = append(changes, Change{Entity: entity, ID: id(oldValue), Kind: Removed})
+			continue
+		}
+
+		change := diffEntity(reflect.ValueOf(oldValue), reflect.ValueOf(newValue))
+		if change.Fields == nil && change.ImagesAdded == nil && change.ImagesRemoved == nil {
+			continue
+		}
+
+		change.Entity = entity
+		change.ID = id(oldValue)
+		change.Kind = Modified
+		changes = append(changes, change)
+	}
+
+	for _, newValue := range new {
+		if !oldIDs[id(newValue)] {
+			changes = append(changes, Change{Entity: entity, ID: id(newValue), Kind: Added})
+		}
+	}
+
+	return changes
+}
+
+// diffEntity compares fields of two versions of the same entity. Images are
+// compared as sets. Nested places of a section are skipped, because they are
+// compared on their own.
+func diffEntity(old, new reflect.Value) Change {
+	var change Change
+
+	for i := 0; i < old.NumField(); i++ {
+		name, ok := jsonName(old.Type().Field(i))
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "places":
+			continue
+		case "images":
+			oldImages := imageNames(old.Field(i))
+			newImages := imageNames(new.Field(i))
+			change.ImagesAdded = missingFrom(oldImages, newImages)
+			change.ImagesRemoved = missingFrom(newImages, oldImages)
+			continue
+		}
+
+		change.Fields = diffValues(change.Fields, name, old.Field(i), new.Field(i))
+	}
+
+	return change
+}
+
+// diffValues appends changes between old and new to fields. Texts, slices of
+// texts and structs containing them are compared element by element, other
+// values as a whole.
+func diffValues(fields []FieldChange, field string, old, new reflect.Value) []FieldChange {
+	if old.Type() == textType {
+		oldText := old.Interface().(Text)
+		newText := new.Interface().(Text)
+
+		langs := make([]string, 0)
+		for lang := range oldText {
+			langs = append(langs, lang)
+		}
+		for lang := range newText {
+			if _, ok := oldText[lang]; !ok {
+				langs = append(langs, lang)
+			}
+		}
+		sort.Strings(langs)
+
+		for _, lang := range langs {
+			if oldText[lang] != newText[lang] {
+				fields = append(fields, FieldChange{Field: field, Lang: lang, Old: oldText[lang], New: newText[lang]})
+			}
+		}
+
+		return fields
+	}
+
+	if !containsText(old.Type()) {
+		oldValue, newValue := diffString(old), diffString(new)
+		if oldValue != newValue {
+			fields = append(fields, FieldChange{Field: field, Old: oldValue, New: newValue})
+		}
+
+		return fields
+	}
+
+	switch old.Kind() {
+	case reflect.Slice:
+		n := max(old.Len(), new.Len())
+		for i := 0; i < n; i++ {
+			zero := reflect.Zero(old.Type().Elem())
+			oldElem, newElem := zero, zero
+			if i < old.Len() {
+				oldElem = old.Index(i)
+			}
+			if i < new.Len() {
+				newElem = new.Index(i)
+			}
+
+			fields = diffValues(fields, fmt.Sprintf("%s[%d]", field, i), oldElem, newElem)
+		}
+	case reflect.Struct:
+		for i := 0; i < old.NumField(); i++ {
+			name, ok := jsonName(old.Type().Field(i))
+			if !ok {
+				continue
+			}
+
+			fields = diffValues(fields, field+"."+name, old.Field(i), new.Field(i))
+		}
+	}
+
+	return fields
+}
+
+// containsText reports whether values of type t contain a Text.
+func containsText(t reflect.Type) bool {
+	if t == textType {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return containsText(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() && containsText(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// diffString returns v as a string suitable for FieldChange.
+func diffString(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+
+	return string(data)
+}
+
+// jsonName returns the name of field in JSON, or false if the field isn't
+// marshalled.
+func jsonName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+
+	return name, true
+}
+
+// imageNames returns names of images in v, which is either []string or
+// []Image.
+func imageNames(v reflect.Value) []string {
+	if images, ok := v.Interface().([]Image); ok {
+		return ImageNames(images)
+	}
+
+	names, _ := v.Interface().([]string)
+	return names
+}
+
+// missingFrom returns elements of b that are not in a, or nil if there are
+// none.
+func missingFrom(a, b []string) []string {
+	inA := make(map[string]bool, len(a))
+	for _, s := range a {
+		inA[s] = true
+	}
+
+	var missing []string
+	for _, s := range b {
+		if !inA[s] {
+			missing = append(missing, s)
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,51 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/models"
+)
+
+func TestDiff(t *testing.T) {
+	old := models.Datafile{
+		Sections: []models.Section{
+			{ID: "zabytki", Name: models.Text{"pl": "Zabytki"}, Places: []models.Place{
+				{ID: "kosciol", Name: models.Text{"pl": "Kościół", "en": "Church"}, Lat: 51.1, Images: []models.Image{{Name: "a.webp"}, {Name: "b.webp"}}},
+				{ID: "mlyn", Name: models.Text{"pl": "Młyn"}},
+			}},
+		},
+		Stories: []models.Story{{ID: "legenda", Name: models.Text{"pl": "Legenda"}}},
+	}
+
+	new := models.Datafile{
+		Sections: []models.Section{
+			{ID: "zabytki", Name: models.Text{"pl": "Zabytki"}, Places: []models.Place{
+				{ID: "kosciol", Name: models.Text{"pl": "Kościół", "en": "Parish church"}, Lat: 51.2, Images: []models.Image{{Name: "b.webp"}, {Name: "c.webp"}}},
+				{ID: "zamek", Name: models.Text{"pl": "Zamek"}},
+			}},
+		},
+		Stories: []models.Story{{ID: "legenda", Name: models.Text{"pl": "Legenda"}}},
+	}
+
+	want := []models.Change{
+		{
+			Entity: "place",
+			ID:     "kosciol",
+			Kind:   models.Modified,
+			Fields: []models.FieldChange{
+				{Field: "name", Lang: "en", Old: "Church", New: "Parish church"},
+				{Field: "lat", Old: "51.1", New: "51.2"},
+			},
+			ImagesAdded:   []string{"c.webp"},
+			ImagesRemoved: []string{"a.webp"},
+		},
+		{Entity: "place", ID: "mlyn", Kind: models.Removed},
+		{Entity: "place", ID: "zamek", Kind: models.Added},
+	}
+
+	got := models.Diff(&old, &new)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+}
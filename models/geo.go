@@ -0,0 +1,138 @@
+package models
+
+import (
+	"math"
+	"sort"
+)
+
+// earthRadius is the mean radius of the Earth in meters.
+const earthRadius = 6371000
+
+// Locations returns coordinates of all places and track points in the
+// datafile.
+func (d *Datafile) Locations() []Location {
+	locations := make([]Location, 0)
+	for _, place := range d.AllPlaces() {
+		locations = append(locations, Location{Lat: place.Lat, Lng: place.Lng})
+	}
+
+	for _, track := range d.Tracks {
+		locations = append(locations, track.Coords...)
+	}
+
+	return locations
+}
+
+// Center returns the center of the datafile's region: the centroid of its
+// places and tracks. Every track counts as a single location (the centroid of
+// its points), so a long track doesn't pull the center away from the places.
+func (d *Datafile) Center() Location {
+	locations := make([]Location, 0)
+	for _, place := range d.AllPlaces() {
+		locations = append(locations, Location{Lat: place.Lat, Lng: place.Lng})
+	}
+
+	for _, track := range d.Tracks {
+		if len(track.Coords) > 0 {
+			locations = append(locations, Centroid(track.Coords))
+		}
+	}
+
+	return Centroid(locations)
+}
+
+// Centroid returns the arithmetic mean of locations, rounded to 5 decimal
+// places (about 1 meter).
+func Centroid(locations []Location) Location {
+	if len(locations) == 0 {
+		return Location{}
+	}
+
+	var lat, lng float64
+	for _, location := range locations {
+		lat += float64(location.Lat)
+		lng += float64(location.Lng)
+	}
+
+	n := float64(len(locations))
+	return Location{Lat: round5(lat / n), Lng: round5(lng / n)}
+}
+
+func round5(x float64) float32 {
+	return float32(math.Round(x*1e5) / 1e5)
+}
+
+// ConvexHull returns the smallest convex polygon containing all locations. The
+// polygon is in counter-clockwise order and is closed, i.e its first vertex is
+// repeated at the end. This holds for fewer than 3 distinct locations too, e.g
+// a single location makes a polygon of two equal vertices. No locations make an
+// empty polygon.
+//
+// Coordinates are treated as if they were on a plane, which is fine for areas
+// of the size of a region.
+func ConvexHull(locations []Location) []Location {
+	points := make([]Location, len(locations))
+	copy(points, locations)
+
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Lng != points[j].Lng {
+			return points[i].Lng < points[j].Lng
+		}
+		return points[i].Lat < points[j].Lat
+	})
+
+	// Remove duplicates, they confuse the algorithm.
+	unique := points[:0]
+	for i, point := range points {
+		if i == 0 || point != points[i-1] {
+			unique = append(unique, point)
+		}
+	}
+	points = unique
+
+	if len(points) < 3 {
+		if len(points) == 0 {
+			return points
+		}
+		return append(points, points[0])
+	}
+
+	// Andrew's monotone chain algorithm.
+	cross := func(o, a, b Location) float64 {
+		return (float64(a.Lng)-float64(o.Lng))*(float64(b.Lat)-float64(o.Lat)) -
+			(float64(a.Lat)-float64(o.Lat))*(float64(b.Lng)-float64(o.Lng))
+	}
+
+	hull := make([]Location, 0, 2*len(points))
+	for _, point := range points {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], point) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, point)
+	}
+
+	lower := len(hull) + 1
+	for i := len(points) - 2; i >= 0; i-- {
+		point := points[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], point) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, point)
+	}
+
+	// The last point is equal to the first one, which closes the polygon.
+	return hull
+}
+
+// Distance returns the great-circle distance between a and b in meters.
+func Distance(a, b Location) float64 {
+	lat1 := float64(a.Lat) * math.Pi / 180
+	lat2 := float64(b.Lat) * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := float64(b.Lng-a.Lng) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,100 @@
+package models_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/models"
+)
+
+func TestCentroid(t *testing.T) {
+	locations := []models.Location{
+		{Lat: 50, Lng: 18},
+		{Lat: 51, Lng: 18},
+		{Lat: 51, Lng: 19},
+	}
+
+	got := models.Centroid(locations)
+	want := models.Location{Lat: 50.66667, Lng: 18.33333}
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDatafileCenter(t *testing.T) {
+	track := models.Track{}
+	for i := range 11 {
+		track.Coords = append(track.Coords, models.Location{Lat: 51, Lng: 18 + float32(i)*0.002})
+	}
+
+	datafile := models.Datafile{
+		Sections: []models.Section{{Places: []models.Place{{Lat: 50, Lng: 18}, {Lat: 50, Lng: 18.02}}}},
+		Tracks:   []models.Track{track},
+	}
+
+	// The track counts as much as a single place, no matter how many points
+	// it has.
+	got := datafile.Center()
+	want := models.Location{Lat: 50.33333, Lng: 18.01}
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+	locations := []models.Location{
+		{Lat: 50, Lng: 18},
+		{Lat: 50.5, Lng: 18.5}, // Inside.
+		{Lat: 51, Lng: 18},
+		{Lat: 50, Lng: 18.5}, // On the edge.
+		{Lat: 51, Lng: 19},
+		{Lat: 50, Lng: 19},
+		{Lat: 50, Lng: 19}, // Duplicate.
+	}
+
+	got := models.ConvexHull(locations)
+	want := []models.Location{
+		{Lat: 50, Lng: 18},
+		{Lat: 50, Lng: 19},
+		{Lat: 51, Lng: 19},
+		{Lat: 51, Lng: 18},
+		{Lat: 50, Lng: 18},
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestConvexHullFewLocations(t *testing.T) {
+	a := models.Location{Lat: 50, Lng: 18}
+	b := models.Location{Lat: 51, Lng: 19}
+
+	tests := []struct {
+		locations []models.Location
+		want      []models.Location
+	}{
+		{[]models.Location{}, []models.Location{}},
+		{[]models.Location{a}, []models.Location{a, a}},
+		{[]models.Location{b, a, b}, []models.Location{a, b, a}},
+	}
+
+	for _, test := range tests {
+		got := models.ConvexHull(test.locations)
+		if !cmp.Equal(got, test.want) {
+			t.Errorf("got %v from %v, want %v", got, test.locations, test.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	// Rudy and Kuźnia Raciborska are about 6.5 km apart.
+	rudy := models.Location{Lat: 50.19, Lng: 18.44}
+	kuznia := models.Location{Lat: 50.2, Lng: 18.35}
+
+	got := models.Distance(rudy, kuznia)
+	if math.Abs(got-6520) > 100 {
+		t.Errorf("got %.0f m, want about 6520 m", got)
+	}
+}
@@ -0,0 +1,268 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"path"
+	"strconv"
+
+	"github.com/bbrks/go-blurhash"
+	_ "github.com/jdeng/goheif"
+	"github.com/opentouristics/database-tools/parallel"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// FocusFile is the name of the file in the "images/original" directory which
+// maps names of images (without extension) to their focus points, e.g.
+//
+//	{"ic_kosciol": {"x": 0.5, "y": 0.3}}
+//
+// Coordinates are fractions of the width and height of the upright image.
+// Icons are cropped around their focus point, or around their center if they
+// have none.
+const FocusFile = "focus.json"
+
+// VariantWidths are widths of smaller versions of images, which the app picks
+// from depending on where an image is shown. Variants exist only in compressed
+// quality, and only those narrower than the original are made.
+var VariantWidths = []int{256, 768, 1600}
+
+// VariantSeparator separates the name of an image from the width of its
+// variant, e.g kosciol_1@256w, or from the size of an additional icon. It's not
+// allowed in names of images, so that no image is mistaken for a variant.
+const VariantSeparator = "@"
+
+// VariantName returns name of the variant of image called name which is width
+// pixels wide.
+func VariantName(name string, width int) string {
+	return name + VariantSeparator + strconv.Itoa(width) + "w"
+}
+
+// Image is an image of a place or a story.
+//
+// In the datafile's source, images are listed by their names only. The rest is
+// filled in from the image files when the datafile is generated (see
+// Datafile.DescribeImages).
+type Image struct {
+	Name     string         `json:"name" description:"Filename of the image, without extension."`
+	Width    int            `json:"width" description:"Width of the image in pixels."`
+	Height   int            `json:"height" description:"Height of the image in pixels."`
+	Variants []ImageVariant `json:"variants" description:"Smaller versions of the image, from the narrowest."`
+	Blurhash string         `json:"blurhash" description:"BlurHash of the image, to be shown while it's loading."`
+}
+
+// ImageVariant is a smaller version of an image.
+type ImageVariant struct {
+	Name   string `json:"name" description:"Filename of the variant, without extension."`
+	Width  int    `json:"width" description:"Width of the variant in pixels."`
+	Height int    `json:"height" description:"Height of the variant in pixels."`
+}
+
+// UnmarshalJSON accepts both an image's name, as in the datafile's source, and
+// an object, as in the generated datafile.
+func (img *Image) UnmarshalJSON(data []byte) error {
+	var name string
+	if json.Unmarshal(data, &name) == nil {
+		*img = Image{Name: name}
+		return nil
+	}
+
+	// A distinct type, so that this method isn't called recursively.
+	type plainImage Image
+	return json.Unmarshal(data, (*plainImage)(img))
+}
+
+// ImageNames returns names of images.
+func ImageNames(images []Image) []string {
+	names := make([]string, 0, len(images))
+	for _, img := range images {
+		names = append(names, img.Name)
+	}
+
+	return names
+}
+
+// findImages finds main files of images in quality. dir is the directory of a
+// place or story that contains the "images" directory. It returns their paths
+// in the order of images.
+//
+// Image files aren't decoded here, so that parsing stays fast. Dimensions,
+// variants and blurhashes are filled in by Datafile.DescribeImages.
+func findImages(fsys fs.FS, dir string, quality Quality, images []Image) ([]string, error) {
+	paths := make([]string, 0, len(images))
+	for i := range images {
+		imagePath, err := FindImage(fsys, dir, quality, images[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, imagePath)
+
+		images[i].Variants = make([]ImageVariant, 0)
+	}
+
+	return paths, nil
+}
+
+// DescribeImages fills in dimensions, variants and blurhashes of images of
+// places and stories, and adds files of the variants to their assets. It
+// decodes image files, so it's done only when the datafile is generated, by at
+// most jobs goroutines at once. fsys must be the one the datafile was parsed
+// from.
+func (d *Datafile) DescribeImages(fsys fs.FS, jobs int) error {
+	type imageRef struct {
+		img   *Image
+		path  string    // Path of the image's main file.
+		paths *[]string // Image paths of the place or story the image belongs to.
+	}
+
+	refs := make([]imageRef, 0)
+	for i := range d.Sections {
+		for j := range d.Sections[i].Places {
+			p := &d.Sections[i].Places[j]
+			for k := range p.Images {
+				refs = append(refs, imageRef{img: &p.Images[k], path: p.imagePaths[k], paths: &p.imagePaths})
+			}
+		}
+	}
+	for i := range d.Stories {
+		s := &d.Stories[i]
+		for k := range s.Images {
+			refs = append(refs, imageRef{img: &s.Images[k], path: s.imagePaths[k], paths: &s.imagePaths})
+		}
+	}
+
+	variantPaths := make([][]string, len(refs))
+	err := parallel.ForEach(len(refs), jobs, func(i int) error {
+		paths, err := describeImage(fsys, refs[i].img, refs[i].path)
+		if err != nil {
+			return fmt.Errorf("image %s: %w", refs[i].img.Name, err)
+		}
+
+		variantPaths[i] = paths
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, ref := range refs {
+		*ref.paths = append(*ref.paths, variantPaths[i]...)
+	}
+
+	return nil
+}
+
+// describeImage fills in dimensions, variants and blurhash of img, whose main
+// file is at imagePath. Variants are looked for next to it. It returns paths of
+// the variants' files.
+func describeImage(fsys fs.FS, img *Image, imagePath string) ([]string, error) {
+	var err error
+	img.Width, img.Height, err = imageSize(fsys, imagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, ext := path.Dir(imagePath), path.Ext(imagePath)
+	paths := make([]string, 0)
+	img.Variants = make([]ImageVariant, 0)
+	for _, width := range VariantWidths {
+		variant := ImageVariant{Name: VariantName(img.Name, width)}
+		variantPath := path.Join(dir, variant.Name+ext)
+		if _, err := fs.Stat(fsys, variantPath); err != nil {
+			continue
+		}
+
+		variant.Width, variant.Height, err = imageSize(fsys, variantPath)
+		if err != nil {
+			return nil, err
+		}
+
+		img.Variants = append(img.Variants, variant)
+		paths = append(paths, variantPath)
+	}
+
+	img.Blurhash, err = imageBlurhash(fsys, blurhashSource(fsys, img.Name, imagePath))
+	if err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// blurhashSource returns path of the smallest compressed file of the image
+// called name, because it's the fastest to decode. If there's none, e.g. when
+// only full-resolution images are made, imagePath is returned.
+func blurhashSource(fsys fs.FS, name string, imagePath string) string {
+	compressedDir := path.Join(path.Dir(path.Dir(imagePath)), Compressed.Dir())
+
+	candidates := make([]string, 0, len(VariantWidths)+1)
+	for _, width := range VariantWidths {
+		candidates = append(candidates, path.Join(compressedDir, VariantName(name, width)+".webp"))
+	}
+	candidates = append(candidates, path.Join(compressedDir, name+".webp"))
+
+	for _, candidate := range candidates {
+		if _, err := fs.Stat(fsys, candidate); err == nil {
+			return candidate
+		}
+	}
+
+	return imagePath
+}
+
+func imageSize(fsys fs.FS, name string) (int, int, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, fmt.Errorf("decode %s: %w", name, err)
+	}
+
+	return config.Width, config.Height, nil
+}
+
+func imageBlurhash(fsys fs.FS, name string) (string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return "", fmt.Errorf("decode %s: %w", name, err)
+	}
+
+	hash, err := Blurhash(img)
+	if err != nil {
+		return "", fmt.Errorf("blurhash of %s: %w", name, err)
+	}
+
+	return hash, nil
+}
+
+// blurhashSize is the width of the image from which blurhash is computed.
+// Blurhash is blurry anyway, so bigger images would only make it slower.
+const blurhashSize = 64
+
+// Blurhash returns the BlurHash of img, with 4x3 components.
+func Blurhash(img image.Image) (string, error) {
+	bounds := img.Bounds()
+	if bounds.Dx() > blurhashSize {
+		h := max(1, bounds.Dy()*blurhashSize/bounds.Dx())
+		small := image.NewNRGBA(image.Rect(0, 0, blurhashSize, h))
+		draw.ApproxBiLinear.Scale(small, small.Bounds(), img, bounds, draw.Src, nil)
+		img = small
+	}
+
+	return blurhash.Encode(4, 3, img)
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// Load loads a datafile from source, which is one of:
+//
+//   - a generated datafile directory, e.g generated/rudy
+//   - a compressed datafile, e.g compressed/rudy.zip
+//   - a datafile source directory, e.g datafiles/datafile-rudy
+//   - a datafile source directory at a git revision, e.g
+//     datafiles/datafile-rudy@v1.2.0
+//
+// A source is at a git revision only if the part before "@" is a directory and
+// the part after it is a revision of its repository, so paths that contain
+// "@" can be loaded too.
+//
+// Sources are parsed by at most jobs goroutines at once.
+func Load(source string, jobs int) (Datafile, error) {
+	if dir, rev, ok := splitRevision(source); ok {
+		fsys, err := gitFS(dir, rev)
+		if err != nil {
+			return Datafile{}, err
+		}
+
+		return ParseDatafile(fsys, Compressed, jobs, false)
+	}
+
+	if strings.HasSuffix(source, ".zip") {
+		zipReader, err := zip.OpenReader(source)
+		if err != nil {
+			return Datafile{}, err
+		}
+		defer zipReader.Close()
+
+		// Files of a compressed datafile are inside of a directory named after
+		// the region.
+		matches, err := fs.Glob(zipReader, "*/data.json")
+		if err != nil || len(matches) == 0 {
+			return ParseGeneratedDatafile(zipReader)
+		}
+
+		fsys, err := fs.Sub(zipReader, path.Dir(matches[0]))
+		if err != nil {
+			return Datafile{}, err
+		}
+
+		return ParseGeneratedDatafile(fsys)
+	}
+
+	if _, err := os.Stat(source); err != nil {
+		if strings.Contains(source, "@") {
+			return Datafile{}, fmt.Errorf("%w, and it's not a directory at a git revision of its repository", err)
+		}
+		return Datafile{}, err
+	}
+
+	fsys := os.DirFS(source)
+	if _, err := fs.Stat(fsys, "data.json"); err == nil {
+		return ParseGeneratedDatafile(fsys)
+	}
+
+	return ParseDatafile(fsys, Compressed, jobs, false)
+}
+
+// splitRevision splits source into a directory and a git revision, if it's a
+// datafile source directory at a git revision. An existing path is never
+// split. Revisions may contain "@" too (e.g HEAD@{1}), so every "@" is tried,
+// starting from the last one.
+func splitRevision(source string) (string, string, bool) {
+	if _, err := os.Stat(source); err == nil {
+		return "", "", false
+	}
+
+	for i := strings.LastIndex(source, "@"); i >= 0; i = strings.LastIndex(source[:i], "@") {
+		dir, rev := source[:i], source[i+1:]
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() || rev == "" {
+			continue
+		}
+
+		cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+		cmd.Dir = dir
+		if cmd.Run() == nil {
+			return dir, rev, true
+		}
+	}
+
+	return "", "", false
+}
+
+// gitFS returns files of datafile source directory dir at git revision rev.
+func gitFS(dir string, rev string) (fs.FS, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-prefix")
+	cmd.Dir = dir
+	prefix, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("find %s in its git repository: %v", dir, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd = exec.Command("git", "archive", "--format=zip", rev+":"+strings.TrimSpace(string(prefix)))
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("archive %s at %s: %v: %s", dir, rev, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return zip.NewReader(bytes.NewReader(stdout.Bytes()), int64(stdout.Len()))
+}
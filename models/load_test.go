@@ -0,0 +1,97 @@
+package models_test
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/sourcetest"
+)
+
+func TestLoadDir(t *testing.T) {
+	// "@" in the path must not be mistaken for a git revision.
+	dir := filepath.Join(t.TempDir(), "datafile@rudy")
+	sourcetest.Write(t, dir, sourcetest.Datafile())
+
+	datafile, err := models.Load(dir, 1)
+	if err != nil {
+		t.Fatalf("failed to load datafile source: %v", err)
+	}
+
+	if got, want := datafile.Meta.RegionID, "rudy"; got != want {
+		t.Errorf("got region %q, want %q", got, want)
+	}
+}
+
+func TestLoadZip(t *testing.T) {
+	source, err := models.ParseDatafile(sourcetest.Datafile(), models.Compressed, 1, false)
+	if err != nil {
+		t.Fatalf("failed to parse datafile: %v", err)
+	}
+
+	data, err := json.Marshal(source)
+	if err != nil {
+		t.Fatalf("failed to marshal datafile: %v", err)
+	}
+
+	zipPath := filepath.Join(t.TempDir(), "rudy.zip")
+	file, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipWriter := zip.NewWriter(file)
+	w, err := zipWriter.Create("rudy/data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	zipWriter.Close()
+	file.Close()
+
+	datafile, err := models.Load(zipPath, 1)
+	if err != nil {
+		t.Fatalf("failed to load compressed datafile: %v", err)
+	}
+
+	if got, want := len(datafile.AllPlaces()), 1; got != want {
+		t.Errorf("got %d places, want %d", got, want)
+	}
+}
+
+func TestLoadRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	sourcetest.Write(t, dir, sourcetest.Datafile())
+	for _, args := range [][]string{{"init", "-q"}, {"add", "-A"}, {"commit", "-q", "-m", "Initial"}} {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	sourcetest.Write(t, dir, fstest.MapFS{"meta/content/pl/name.txt": sourcetest.File("Rudy Raciborskie\n")})
+
+	datafile, err := models.Load(dir+"@HEAD", 1)
+	if err != nil {
+		t.Fatalf("failed to load datafile source at HEAD: %v", err)
+	}
+
+	if got, want := datafile.Meta.RegionName["pl"], "Rudy"; got != want {
+		t.Errorf("got region name %q at HEAD, want %q", got, want)
+	}
+
+	_, err = models.Load(dir+"@no-such-revision", 1)
+	if err == nil {
+		t.Error("got no error for an unknown revision")
+	}
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	textType      = reflect.TypeOf(Text{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// PackID returns ID of the single-language pack of region in lang.
+func PackID(regionID string, lang string) string {
+	return regionID + "." + lang
+}
+
+// Languages returns sorted codes of all languages used in texts of the
+// datafile.
+func (d *Datafile) Languages() []string {
+	seen := make(map[string]bool)
+	walkTexts(reflect.ValueOf(d).Elem(), func(text Text) {
+		for lang := range text {
+			seen[lang] = true
+		}
+	})
+
+	langs := make([]string, 0, len(seen))
+	for lang := range seen {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	return langs
+}
+
+// walkTexts calls fn for every Text reachable from v.
+func walkTexts(v reflect.Value, fn func(Text)) {
+	if v.Type() == textType {
+		fn(v.Interface().(Text))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			walkTexts(v.Elem(), fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkTexts(v.Index(i), fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				walkTexts(v.Field(i), fn)
+			}
+		}
+	}
+}
+
+// MarshalLocalized returns indented JSON encoding of v, in which every Text is
+// flattened to a string in the first of langs it is available in (see
+// Text.Localized).
+func MarshalLocalized(v any, langs ...string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := marshalLocalized(&buf, reflect.ValueOf(v), langs)
+	if err != nil {
+		return nil, err
+	}
+
+	var indented bytes.Buffer
+	err = json.Indent(&indented, buf.Bytes(), "", "	")
+	if err != nil {
+		return nil, err
+	}
+
+	return indented.Bytes(), nil
+}
+
+func marshalLocalized(buf *bytes.Buffer, v reflect.Value, langs []string) error {
+	if v.Type() == textType {
+		return marshalValue(buf, v.Interface().(Text).Localized(langs...))
+	}
+
+	if v.Type().Implements(marshalerType) {
+		return marshalValue(buf, v.Interface())
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return marshalLocalized(buf, v.Elem(), langs)
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			err := marshalLocalized(buf, v.Index(i), langs)
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case reflect.Struct:
+		return marshalStructLocalized(buf, v, langs)
+	}
+
+	return marshalValue(buf, v.Interface())
+}
+
+func marshalStructLocalized(buf *bytes.Buffer, v reflect.Value, langs []string) error {
+	buf.WriteByte('{')
+
+	first := true
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		if strings.Contains(options, "omitempty") && v.Field(i).IsZero() {
+			continue
+		}
+
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		err := marshalValue(buf, name)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(':')
+
+		err = marshalLocalized(buf, v.Field(i), langs)
+		if err != nil {
+			return fmt.Errorf("marshal field %s: %w", field.Name, err)
+		}
+	}
+
+	buf.WriteByte('}')
+	return nil
+}
+
+func marshalValue(buf *bytes.Buffer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	buf.Write(data)
+	return nil
+}
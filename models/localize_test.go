@@ -0,0 +1,64 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/sourcetest"
+)
+
+func TestLanguages(t *testing.T) {
+	datafile, err := models.ParseDatafile(sourcetest.Datafile(), models.Compressed, 1, false)
+	if err != nil {
+		t.Fatalf("failed to parse datafile: %v", err)
+	}
+
+	if got, want := datafile.Languages(), []string{"en", "pl"}; !cmp.Equal(got, want) {
+		t.Errorf("got languages %q, want %q", got, want)
+	}
+}
+
+func TestMarshalLocalized(t *testing.T) {
+	section := models.Section{
+		ID:        "zabytki",
+		Name:      models.Text{"pl": "Zabytki", "en": "Monuments"},
+		QuickInfo: models.Text{"pl": "Stare budynki"},
+		Places: []models.Place{
+			{ID: "kosciol", Name: models.Text{"pl": "Kościół", "en": "Church"}, Headers: []models.Text{{"pl": "Historia"}}},
+		},
+	}
+
+	data, err := models.MarshalLocalized(section, "en", "pl")
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	var got map[string]any
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	if got["name"] != "Monuments" {
+		t.Errorf("got name %v, want Monuments", got["name"])
+	}
+
+	if got["quick_info"] != "Stare budynki" {
+		t.Errorf("got quick info %v, want the Polish fallback", got["quick_info"])
+	}
+
+	place := got["places"].([]any)[0].(map[string]any)
+	if place["name"] != "Church" {
+		t.Errorf("got place name %v, want Church", place["name"])
+	}
+
+	if !cmp.Equal(place["headers"], []any{"Historia"}) {
+		t.Errorf("got place headers %v, want [Historia]", place["headers"])
+	}
+
+	if place["website_url"] != nil {
+		t.Errorf("got website url %v, want nil", place["website_url"])
+	}
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"strings"
 	"time"
 
@@ -12,47 +13,58 @@ import (
 // Meta represents the JSON object in the beginning of data.json file.
 type Meta struct {
 	// Short, lowercase ID of the datafile's region.
-	RegionID string `json:"region_id"`
+	RegionID string `json:"region_id" description:"Unique ID of the region."`
 
 	// Full localized name of the datafile's region.
-	RegionName Text `json:"region_name"`
+	RegionName Text `json:"region_name" description:"Localized name of the region."`
 
 	// Center of the Region
-	Center Location `json:"center"`
+	Center Location `json:"center" description:"Latitude and longitude of the coarse center of the region."`
 
 	// Time of datafile generation. It is present only in generated datafile i.e
 	// after the "generate" program has been run.
-	GeneratedAt time.Time `json:"generated_at"`
+	GeneratedAt time.Time `json:"generated_at" description:"Timestamp when the datafile was generated. Format: ISO8601 (e.g 2021-04-06T22:57:38Z)"`
 
 	// People who somehow helped with creating the datafile.
-	Contributors []string `json:"contributors"`
+	Contributors []string `json:"contributors" description:"People who contributed to the project in some way."`
 
 	// Some featured places present in the datafile.
-	Featured []string `json:"featured"`
+	Featured []string `json:"featured" description:"IDs of featured places."`
 
 	// Resources (websites, books) which provided data in the datafile.
-	Sources []Link `json:"sources"`
+	Sources []Link `json:"sources" description:"Books, articles and websites that were used to provide information."`
 
 	// Related resources which might interest people using this datafile.
-	Links []Link `json:"links"`
+	Links []Link `json:"links" description:"Links leading to interesting websites, related to the region."`
 
 	// Hash that identifies the commit from which this datafile was generated.
-	CommitHash string `json:"commit_hash"`
+	CommitHash string `json:"commit_hash" description:"Hash of the commit from which the datafile was generated."`
 
 	// Non-nil only for production datafiles.
-	CommitTag *string `json:"commit_tag"`
+	CommitTag *string `json:"commit_tag" description:"Tag of the commit from which the datafile was generated. Non-null only for production datafiles."`
 
 	// Count of places in all sections.
-	PlaceCount int `json:"place_count"`
+	PlaceCount int `json:"place_count" description:"Count of places in all sections."`
 
 	// Places that are on the edges.
-	Bounds []Location `json:"bounds"`
+	Bounds []Location `json:"bounds" description:"Places that are on the edges of the region."`
+
+	// Languages in which texts in the datafile are available.
+	Languages []string `json:"languages" description:"Codes of languages in which texts are available."`
+
+	// Places that changed since the previous version.
+	Changes *Changelog `json:"changes" description:"Places that changed since the previous version of the datafile. Null if it wasn't compared with any."`
 }
 
-// Parse parses datafile's metadata and assigns it to meta struct pointed to by
-// m.
-func (m *Meta) Parse() error {
-	name, err := readers.ReadLocalizedFiles("name.txt")
+// Parse parses datafile's metadata from directory dir of fsys and assigns it to
+// meta struct pointed to by m.
+func (m *Meta) Parse(fsys fs.FS, dir string) error {
+	metaFS, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	name, err := readers.ReadLocalizedFiles(metaFS, "name.txt")
 	if err != nil {
 		return err
 	}
@@ -62,7 +74,7 @@ func (m *Meta) Parse() error {
 	}
 	m.RegionName = name
 
-	data, err := readers.ReadFromFile("data.json")
+	data, err := fs.ReadFile(metaFS, "data.json")
 	if err != nil {
 		return err
 	}
@@ -72,20 +84,31 @@ func (m *Meta) Parse() error {
 		return fmt.Errorf("unmarshal JSON: %v", err)
 	}
 
+	// Lists which are missing in data.json must still be arrays in the output.
+	if m.Contributors == nil {
+		m.Contributors = make([]string, 0)
+	}
+	if m.Featured == nil {
+		m.Featured = make([]string, 0)
+	}
+	if m.Sources == nil {
+		m.Sources = make([]Link, 0)
+	}
+	if m.Links == nil {
+		m.Links = make([]Link, 0)
+	}
+	if m.Bounds == nil {
+		m.Bounds = make([]Location, 0)
+	}
+
 	return nil
 }
 
 // ParseFromGenerated parses metadata of the datafile in the generated
-// directory. It looks for data.json in the current dir, parses it and and
+// directory. It looks for data.json in the root of fsys, parses it and and
 // assigns it to meta struct pointed to by m.
-func (m *Meta) ParseFromGenerated() error {
-	datafileData, err := readers.ReadFromFile("data.json")
-	if err != nil {
-		return err
-	}
-
-	var datafile Datafile
-	err = json.Unmarshal(datafileData, &datafile)
+func (m *Meta) ParseFromGenerated(fsys fs.FS) error {
+	datafile, err := ParseGeneratedDatafile(fsys)
 	if err != nil {
 		return err
 	}
@@ -8,21 +8,11 @@ type Action struct {
 
 // Location represents single a point in the real world.
 type Location struct {
-	Lat float32 `json:"lat" firestore:"lat"`
-	Lng float32 `json:"lng" firestore:"lng"`
+	Lat float32 `json:"lat" firestore:"lat" description:"Latitude. Format: DDD.DDDDD° (Decimal Degrees)"`
+	Lng float32 `json:"lng" firestore:"lng" description:"Longitude. Format: DDD.DDDDD° (Decimal Degrees)"`
 }
 
 type Link struct {
 	Name       string `json:"name"`
 	WebsiteURL string `json:"website_url"`
 }
-
-// Quality represents quality of an image.
-type Quality int
-
-const (
-	// Compressed quality is most often used.
-	Compressed = iota + 1
-	// Original quality represents full, uncompressed image.
-	Original
-)
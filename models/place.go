@@ -4,8 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
 	"strings"
 
 	"github.com/opentouristics/database-tools/formatters"
@@ -14,29 +13,33 @@ import (
 
 // Place represents single place in real world.
 type Place struct {
-	ID          string   `json:"id"`
-	Name        Text     `json:"name"`
-	Section     string   `json:"section"`
-	Icon        string   `json:"icon"`
-	QuickInfo   Text     `json:"quick_info"`
-	Overview    Text     `json:"overview"`
-	Lat         float32  `json:"lat"`
-	Lng         float32  `json:"lng"`
-	WebsiteURL  *string  `json:"website_url"`
-	FacebookURL *string  `json:"facebook_url"`
+	ID          string   `json:"id" description:"Unique ID of the place in the datafile."`
+	Name        Text     `json:"name" description:"Localized name of the place."`
+	Section     string   `json:"section" description:"ID of the section the place belongs to."`
+	Icon        string   `json:"icon" description:"Filename of the place's icon."`
+	QuickInfo   Text     `json:"quick_info" description:"Short and brief description of the place."`
+	Overview    Text     `json:"overview" description:"Longer description of the place, about 2-5x longer than quick_info."`
+	Lat         float32  `json:"lat" description:"Latitude of the place. Format: DDD.DDDDD° (Decimal Degrees)"`
+	Lng         float32  `json:"lng" description:"Longitude of the place. Format: DDD.DDDDD° (Decimal Degrees)"`
+	WebsiteURL  *string  `json:"website_url" description:"URL of the website that has more info about the place."`
+	FacebookURL *string  `json:"facebook_url" description:"URL of the place's Facebook page."`
 	Headers     []Text   `json:"headers"`
 	Content     []Text   `json:"content"`
-	Actions     []Action `json:"actions"`
-	Images      []string `json:"images"`
+	Actions     []Action `json:"actions" description:"Links to interesting resources related to the place."`
+	Images      []Image  `json:"images" description:"The place's images."`
 	imagePaths  []string
 }
 
-// Parse parses place data from its directory and assigns
-// it to track pointed to by p. It must be used directly
-// in the place's directory.
-func (p *Place) Parse(verbose bool) error {
+// Parse parses place data from directory dir of fsys and assigns it to place
+// pointed to by p. Paths of images in quality are collected.
+func (p *Place) Parse(fsys fs.FS, dir string, quality Quality, verbose bool) error {
+	placeFS, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return err
+	}
+
 	// Technical metadata
-	data, err := readers.ReadFromFile("data.json")
+	data, err := fs.ReadFile(placeFS, "data.json")
 	if err != nil {
 		return err
 	}
@@ -46,25 +49,29 @@ func (p *Place) Parse(verbose bool) error {
 		return err
 	}
 
-	err = p.makeImagePaths(Compressed)
+	if p.Images == nil {
+		p.Images = make([]Image, 0)
+	}
+
+	err = p.makeImagePaths(fsys, dir, quality)
 	if err != nil {
 		return fmt.Errorf("make image paths for place %s: %w", p.ID, err)
 	}
 
 	// Content
-	name, err := readers.ReadLocalizedFiles("name.txt")
+	name, err := readers.ReadLocalizedFiles(placeFS, "name.txt")
 	if err != nil {
 		return fmt.Errorf("read localized name: %v", err)
 	}
 	p.Name = formatters.ToContent(name)
 
-	quickInfo, err := readers.ReadLocalizedFiles("quick_info.txt")
+	quickInfo, err := readers.ReadLocalizedFiles(placeFS, "quick_info.txt")
 	if err != nil {
 		return fmt.Errorf("read localized quick info: %v", err)
 	}
 	p.QuickInfo = formatters.ToContent(quickInfo)
 
-	overview, err := readers.ReadLocalizedFiles("overview.txt")
+	overview, err := readers.ReadLocalizedFiles(placeFS, "overview.txt")
 	if err != nil {
 		return fmt.Errorf("read localized overview: %v", err)
 	}
@@ -74,24 +81,13 @@ func (p *Place) Parse(verbose bool) error {
 	p.Headers = make([]Text, 0)
 	p.Content = make([]Text, 0)
 
-	entries, err := os.ReadDir("content/pl")
+	textFiles, err := readers.LocalizedFileNames(placeFS, "text_")
 	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
-	}
-
-	textFiles := make([]string, 0)
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		if strings.HasPrefix(entry.Name(), "text_") {
-			textFiles = append(textFiles, entry.Name())
-		}
+		return err
 	}
 
 	for _, textFile := range textFiles {
-		text, err := readers.ReadLocalizedFiles(textFile)
+		text, err := readers.ReadLocalizedFiles(placeFS, textFile)
 		if err != nil {
 			return err
 		}
@@ -104,7 +100,7 @@ func (p *Place) Parse(verbose bool) error {
 
 	// Actions
 	p.Actions = make([]Action, 0)
-	err = p.makeActions(verbose)
+	err = p.makeActions(placeFS, verbose)
 	if err != nil {
 		return fmt.Errorf("make actions for place %s: %w", p.ID, err)
 	}
@@ -112,8 +108,8 @@ func (p *Place) Parse(verbose bool) error {
 	return nil
 }
 
-func (p *Place) makeActions(verbose bool) error {
-	actionValuesFile, err := readers.ReadFromFile("actions.json")
+func (p *Place) makeActions(fsys fs.FS, verbose bool) error {
+	actionValuesFile, err := fs.ReadFile(fsys, "actions.json")
 	if err != nil {
 		if verbose {
 			fmt.Printf(
@@ -136,25 +132,14 @@ func (p *Place) makeActions(verbose bool) error {
 	}
 
 	// Read action name for every available language
-	entries, err := os.ReadDir("content/pl")
+	actionNameFiles, err := readers.LocalizedFileNames(fsys, "action_")
 	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
-	}
-
-	actionNameFiles := make([]string, 0)
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		if strings.HasPrefix(entry.Name(), "action_") {
-			actionNameFiles = append(actionNameFiles, entry.Name())
-		}
+		return err
 	}
 
 	actionNames := make([]Text, 0)
 	for _, actionNameFile := range actionNameFiles {
-		text, err := readers.ReadLocalizedFiles(actionNameFile)
+		text, err := readers.ReadLocalizedFiles(fsys, actionNameFile)
 		if err != nil {
 			return err
 		}
@@ -178,39 +163,31 @@ func (p *Place) makeActions(verbose bool) error {
 	return nil
 }
 
-func (p *Place) makeImagePaths(quality Quality) error {
-	workingDir, err := os.Getwd()
+func (p *Place) makeImagePaths(fsys fs.FS, dir string, quality Quality) error {
+	// p.Images were set when the place was parsed from its JSON
+	imagePaths, err := findImages(fsys, dir, quality, p.Images)
 	if err != nil {
-		return fmt.Errorf("get working dir: %w", err)
-	}
-
-	var qualityDir string
-	if quality == Compressed {
-		qualityDir = "compressed"
-	} else if quality == Original {
-		qualityDir = "original"
-	}
-
-	// s.Images were set when the story was parsed from its JSON
-	for _, image := range p.Images {
-		absImagePath := filepath.Join(workingDir, "images", qualityDir, image+".webp")
-
-		if _, err := os.Stat(absImagePath); err != nil {
-			return fmt.Errorf("image at %s does not exist: %w", absImagePath, err)
-		}
-
-		p.imagePaths = append(p.imagePaths, absImagePath)
+		return err
 	}
+	p.imagePaths = append(p.imagePaths, imagePaths...)
 
 	// Add icon
-	absIconPath := filepath.Join(workingDir, "images", qualityDir, p.Icon+".webp")
-	p.imagePaths = append(p.imagePaths, absIconPath)
+	iconPath, err := FindImage(fsys, dir, quality, p.Icon)
+	if err != nil {
+		return fmt.Errorf("icon: %w", err)
+	}
+	p.imagePaths = append(p.imagePaths, iconPath)
 
 	return nil
 }
 
-// ImagePaths returns paths of all images of place p. They are
-// specific to your machine!
+// ImagePaths returns paths of all images of place p. They are relative to the
+// root of the datafile's file system.
 func (p *Place) ImagePaths() []string {
 	return p.imagePaths
 }
+
+// Assets returns files referenced by place p.
+func (p *Place) Assets() []Asset {
+	return imageAssets("place "+p.ID, p.imagePaths)
+}
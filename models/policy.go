@@ -0,0 +1,175 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"slices"
+)
+
+// LanguagePolicyFile is the path to the file with the datafile's language
+// policy, relative to the datafile's root.
+const LanguagePolicyFile = "meta/languages.json"
+
+// LanguagePolicy tells which languages texts of a datafile must be available
+// in, and which language is used when a text isn't available in the requested
+// one.
+type LanguagePolicy struct {
+	// Language in which the datafile is written. It is always required.
+	Primary string `json:"primary"`
+	// Languages in which every text must be available.
+	Required []string `json:"required"`
+	// Languages used, in order, when a text isn't available in the requested
+	// one. It always ends with the primary language.
+	Fallback []string `json:"fallback"`
+}
+
+// DefaultLanguagePolicy returns the policy of datafiles without
+// LanguagePolicyFile: everything must be available in Polish, which is used
+// when there's no text in the requested language.
+func DefaultLanguagePolicy() LanguagePolicy {
+	return LanguagePolicy{Primary: "pl", Required: []string{"pl"}, Fallback: []string{"pl"}}
+}
+
+// ReadLanguagePolicy reads the language policy of the datafile. fsys must be
+// rooted at the datafile's directory. If the datafile has no policy file, the
+// default policy is returned.
+func ReadLanguagePolicy(fsys fs.FS) (LanguagePolicy, error) {
+	data, err := fs.ReadFile(fsys, LanguagePolicyFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return DefaultLanguagePolicy(), nil
+		}
+		return LanguagePolicy{}, err
+	}
+
+	var policy LanguagePolicy
+	err = json.Unmarshal(data, &policy)
+	if err != nil {
+		return policy, fmt.Errorf("unmarshal %s: %w", LanguagePolicyFile, err)
+	}
+
+	if policy.Primary == "" {
+		return policy, fmt.Errorf("%s: primary language is empty", LanguagePolicyFile)
+	}
+
+	if !slices.Contains(policy.Required, policy.Primary) {
+		policy.Required = append([]string{policy.Primary}, policy.Required...)
+	}
+
+	policy.Fallback = slices.DeleteFunc(policy.Fallback, func(lang string) bool { return lang == policy.Primary })
+	policy.Fallback = append(policy.Fallback, policy.Primary)
+
+	return policy, nil
+}
+
+// Chain returns languages in which a text is looked for when it's requested in
+// lang: lang itself, followed by the fallback languages.
+func (p LanguagePolicy) Chain(lang string) []string {
+	chain := []string{lang}
+	for _, fallback := range p.Fallback {
+		if fallback != lang {
+			chain = append(chain, fallback)
+		}
+	}
+
+	return chain
+}
+
+// Check reports every text of datafile d which isn't available in all
+// required languages.
+func (p LanguagePolicy) Check(d *Datafile) error {
+	errs := make([]error, 0)
+	p.walk(d, func(entity string, field string, text Text) {
+		for _, lang := range p.Required {
+			if _, ok := text[lang]; !ok {
+				errs = append(errs, fmt.Errorf("%s: %s has no %s translation", entity, field, lang))
+			}
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// Fill adds texts missing in any of langs to datafile d, taking them from the
+// fallback languages. It returns the number of added texts.
+func (p LanguagePolicy) Fill(d *Datafile, langs []string) int {
+	n := 0
+	p.walk(d, func(entity string, field string, text Text) {
+		for _, lang := range langs {
+			if _, ok := text[lang]; ok {
+				continue
+			}
+
+			value := text.Localized(p.Chain(lang)...)
+			if value != "" {
+				text[lang] = value
+				n++
+			}
+		}
+	})
+
+	return n
+}
+
+// walk calls fn for every non-empty text of datafile d, together with the
+// entity it belongs to and its JSON path in that entity.
+func (p LanguagePolicy) walk(d *Datafile, fn func(entity string, field string, text Text)) {
+	walkFields(reflect.ValueOf(d.Meta), "", func(field string, text Text) { fn("meta", field, text) })
+
+	for _, section := range d.Sections {
+		entity := "section " + section.ID
+		walkFields(reflect.ValueOf(section), "", func(field string, text Text) { fn(entity, field, text) })
+
+		for _, place := range section.Places {
+			entity := "place " + place.ID
+			walkFields(reflect.ValueOf(place), "", func(field string, text Text) { fn(entity, field, text) })
+		}
+	}
+
+	for _, track := range d.Tracks {
+		entity := "track " + track.ID
+		walkFields(reflect.ValueOf(track), "", func(field string, text Text) { fn(entity, field, text) })
+	}
+
+	for _, story := range d.Stories {
+		entity := "story " + story.ID
+		walkFields(reflect.ValueOf(story), "", func(field string, text Text) { fn(entity, field, text) })
+	}
+}
+
+// walkFields calls fn for every non-empty Text reachable from v, together with
+// its JSON path. Places nested in sections are skipped.
+func walkFields(v reflect.Value, field string, fn func(field string, text Text)) {
+	if v.Type() == textType {
+		if v.Len() > 0 {
+			fn(field, v.Interface().(Text))
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			walkFields(v.Elem(), field, fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkFields(v.Index(i), fmt.Sprintf("%s[%d]", field, i), fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := jsonName(v.Type().Field(i))
+			if !ok || v.Type().Field(i).Type == reflect.TypeOf([]Place{}) {
+				continue
+			}
+
+			if field != "" {
+				name = field + "." + name
+			}
+			walkFields(v.Field(i), name, fn)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/sourcetest"
+)
+
+func TestReadLanguagePolicy(t *testing.T) {
+	fsys := sourcetest.Datafile()
+	fsys["meta/languages.json"] = sourcetest.File(`{"primary": "de", "required": ["en"], "fallback": ["de", "en", "pl"]}`)
+
+	policy, err := models.ReadLanguagePolicy(fsys)
+	if err != nil {
+		t.Fatalf("failed to read policy: %v", err)
+	}
+
+	want := models.LanguagePolicy{Primary: "de", Required: []string{"de", "en"}, Fallback: []string{"en", "pl", "de"}}
+	if diff := cmp.Diff(want, policy); diff != "" {
+		t.Errorf("policy mismatch (-want +got):\n%s", diff)
+	}
+
+	if got, want := policy.Chain("en"), []string{"en", "pl", "de"}; !cmp.Equal(got, want) {
+		t.Errorf("got chain %q, want %q", got, want)
+	}
+}
+
+func TestParseDatafileLanguagePolicy(t *testing.T) {
+	fsys := sourcetest.Datafile()
+	fsys["meta/languages.json"] = sourcetest.File(`{"primary": "pl", "required": ["en"]}`)
+
+	_, err := models.ParseDatafile(fsys, models.Compressed, 1, false)
+	if err == nil {
+		t.Fatalf("parsed datafile with texts missing in a required language")
+	}
+
+	if !strings.Contains(err.Error(), "section zabytki: name has no en translation") {
+		t.Errorf("error doesn't mention the missing section name: %v", err)
+	}
+}
+
+func TestLanguagePolicyFill(t *testing.T) {
+	datafile, err := models.ParseDatafile(sourcetest.Datafile(), models.Compressed, 1, false)
+	if err != nil {
+		t.Fatalf("failed to parse datafile: %v", err)
+	}
+
+	datafile.Policy().Fill(&datafile, []string{"en", "pl"})
+
+	section := datafile.Sections[0]
+	if got, want := section.Name, (models.Text{"pl": "Zabytki", "en": "Zabytki"}); !cmp.Equal(got, want) {
+		t.Errorf("got section name %v, want %v", got, want)
+	}
+
+	if got, want := section.Places[0].Name["en"], "Church"; got != want {
+		t.Errorf("got place name %q, want %q", got, want)
+	}
+
+	// Markdown filenames aren't texts, so they aren't filled.
+	delete(datafile.Stories[0].MarkdownFiles, "en")
+	datafile.Policy().Fill(&datafile, []string{"en", "pl"})
+	if got, want := datafile.Stories[0].MarkdownFiles, map[string]string{"pl": "legenda.pl.md"}; !cmp.Equal(got, want) {
+		t.Errorf("got markdown files %v, want %v", got, want)
+	}
+
+	if err := datafile.Policy().Check(&datafile); err != nil {
+		t.Errorf("filled datafile violates the policy: %v", err)
+	}
+}
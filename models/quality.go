@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Quality represents quality of an image.
+type Quality int
+
+const (
+	// Compressed quality is most often used.
+	Compressed Quality = iota + 1
+	// Original quality represents images in their full resolution. They are
+	// made from the originals by "optimize --full", which also strips their
+	// metadata, so the originals themselves are never shipped.
+	Original
+)
+
+// qualityTier describes where images of a particular quality are stored.
+type qualityTier struct {
+	name string
+	// Directory inside the "images" directory of a place or story.
+	dir string
+	// Possible extensions of image files, in order of preference.
+	extensions []string
+}
+
+// qualityTiers lists all supported qualities. To add a new one, add a constant
+// above and describe it here.
+var qualityTiers = map[Quality]qualityTier{
+	Compressed: {name: "compressed", dir: "compressed", extensions: []string{".webp"}},
+	Original:   {name: "original", dir: "full", extensions: []string{".webp"}},
+}
+
+// Valid returns true if q is a supported quality.
+func (q Quality) Valid() bool {
+	_, ok := qualityTiers[q]
+	return ok
+}
+
+// String returns the name of quality q.
+func (q Quality) String() string {
+	if tier, ok := qualityTiers[q]; ok {
+		return tier.name
+	}
+
+	return fmt.Sprintf("Quality(%d)", int(q))
+}
+
+// Dir returns name of the directory in which images of quality q are stored.
+func (q Quality) Dir() string {
+	return qualityTiers[q].dir
+}
+
+// QualityUsage describes all supported qualities, e.g for usage of a
+// command-line flag.
+func QualityUsage() string {
+	qualities := make([]Quality, 0, len(qualityTiers))
+	for quality := range qualityTiers {
+		qualities = append(qualities, quality)
+	}
+	sort.Slice(qualities, func(i, j int) bool { return qualities[i] < qualities[j] })
+
+	descriptions := make([]string, 0, len(qualities))
+	for _, quality := range qualities {
+		descriptions = append(descriptions, fmt.Sprintf("%d - %s", int(quality), quality))
+	}
+
+	return strings.Join(descriptions, ", ")
+}
+
+// FindImage returns path to the file of image called name in quality q. dir
+// is the directory of a place or story that contains the "images" directory.
+func FindImage(fsys fs.FS, dir string, q Quality, name string) (string, error) {
+	tier, ok := qualityTiers[q]
+	if !ok {
+		return "", fmt.Errorf("unknown quality %d", int(q))
+	}
+
+	for _, ext := range tier.extensions {
+		imagePath := path.Join(dir, "images", tier.dir, name+ext)
+		if _, err := fs.Stat(fsys, imagePath); err == nil {
+			return imagePath, nil
+		}
+	}
+
+	imagePath := path.Join(dir, "images", tier.dir, name+tier.extensions[0])
+	return "", fmt.Errorf("image at %s does not exist: %w", imagePath, fs.ErrNotExist)
+}
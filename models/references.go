@@ -0,0 +1,159 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ReferenceProblemKind tells what is wrong with a reference.
+type ReferenceProblemKind string
+
+// Kinds of reference problems.
+const (
+	// Dangling is a reference to an ID which doesn't exist.
+	Dangling ReferenceProblemKind = "dangling"
+	// Duplicate is an ID used by more than one entity.
+	Duplicate ReferenceProblemKind = "duplicate"
+	// Mismatched is a reference which exists, but points to the wrong entity.
+	Mismatched ReferenceProblemKind = "mismatched"
+	// Empty is an empty ID.
+	Empty ReferenceProblemKind = "empty"
+)
+
+// ReferenceProblem is a single broken reference or ID in a datafile.
+type ReferenceProblem struct {
+	Kind     ReferenceProblemKind `json:"kind"`
+	Entity   string               `json:"entity"` // Type of the entity, e.g "place".
+	EntityID string               `json:"entity_id"`
+	Field    string               `json:"field"` // JSON name of the field with the reference or ID.
+	Message  string               `json:"message"`
+}
+
+func (p ReferenceProblem) Error() string {
+	return fmt.Sprintf("%s %s: %s: %s", p.Entity, p.EntityID, p.Field, p.Message)
+}
+
+// IDIndex maps IDs of entities of a datafile to the entities. When an ID is
+// duplicated, the first entity with it is indexed.
+type IDIndex struct {
+	Sections map[string]*Section
+	Places   map[string]*Place
+	Tracks   map[string]*Track
+	Stories  map[string]*Story
+
+	// Section which every place belongs to, keyed by place ID.
+	PlaceSections map[string]*Section
+}
+
+// IndexIDs builds the ID index of datafile d. Pointers in the index point into
+// d.
+func (d *Datafile) IndexIDs() IDIndex {
+	index, _ := d.indexIDs()
+	return index
+}
+
+func (d *Datafile) indexIDs() (IDIndex, []ReferenceProblem) {
+	index := IDIndex{
+		Sections:      make(map[string]*Section),
+		Places:        make(map[string]*Place),
+		Tracks:        make(map[string]*Track),
+		Stories:       make(map[string]*Story),
+		PlaceSections: make(map[string]*Section),
+	}
+	problems := make([]ReferenceProblem, 0)
+
+	// Places, tracks and stories share a single namespace.
+	owners := make(map[string]string)
+	claim := func(entity string, id string) bool {
+		if id == "" {
+			problems = append(problems, ReferenceProblem{Kind: Empty, Entity: entity, Field: "id", Message: "id is empty"})
+			return false
+		}
+
+		if owner, ok := owners[id]; ok {
+			problems = append(problems, ReferenceProblem{Kind: Duplicate, Entity: entity, EntityID: id, Field: "id", Message: "id is already used by " + owner})
+			return false
+		}
+
+		owners[id] = entity
+		return true
+	}
+
+	for i := range d.Sections {
+		section := &d.Sections[i]
+		if section.ID == "" {
+			problems = append(problems, ReferenceProblem{Kind: Empty, Entity: "section", Field: "id", Message: "id is empty"})
+		} else if _, ok := index.Sections[section.ID]; ok {
+			problems = append(problems, ReferenceProblem{Kind: Duplicate, Entity: "section", EntityID: section.ID, Field: "id", Message: "id is already used by another section"})
+		} else {
+			index.Sections[section.ID] = section
+		}
+
+		for j := range section.Places {
+			place := &section.Places[j]
+			if claim("place", place.ID) {
+				index.Places[place.ID] = place
+				index.PlaceSections[place.ID] = section
+			}
+		}
+	}
+
+	for i := range d.Tracks {
+		if claim("track", d.Tracks[i].ID) {
+			index.Tracks[d.Tracks[i].ID] = &d.Tracks[i]
+		}
+	}
+
+	for i := range d.Stories {
+		if claim("story", d.Stories[i].ID) {
+			index.Stories[d.Stories[i].ID] = &d.Stories[i]
+		}
+	}
+
+	return index, problems
+}
+
+// CheckReferences reports IDs of datafile d which are empty or not unique, and
+// references between its entities which can't be resolved:
+//
+//   - IDs of sections must be unique among sections
+//   - IDs of places, tracks and stories must be unique among all of them
+//   - featured places in meta must exist
+//   - the section of every place must be the one it is in
+func (d *Datafile) CheckReferences() []ReferenceProblem {
+	index, problems := d.indexIDs()
+
+	for _, id := range d.Meta.Featured {
+		if _, ok := index.Places[id]; !ok {
+			problems = append(problems, ReferenceProblem{Kind: Dangling, Entity: "meta", EntityID: d.Meta.RegionID, Field: "featured", Message: fmt.Sprintf("place %s does not exist", id)})
+		}
+	}
+
+	for _, section := range d.Sections {
+		for _, place := range section.Places {
+			if place.Section == section.ID {
+				continue
+			}
+
+			kind := Dangling
+			if _, ok := index.Sections[place.Section]; ok {
+				kind = Mismatched
+			}
+
+			problems = append(problems, ReferenceProblem{Kind: kind, Entity: "place", EntityID: place.ID, Field: "section", Message: fmt.Sprintf("is %#v, but the place is in section %s", place.Section, section.ID)})
+		}
+	}
+
+	return problems
+}
+
+// ReferencesError joins problems into a single error, or returns nil if there
+// are none.
+func ReferencesError(problems []ReferenceProblem) error {
+	errs := make([]error, 0, len(problems))
+	for _, problem := range problems {
+		errs = append(errs, problem)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/sourcetest"
+)
+
+func TestCheckReferences(t *testing.T) {
+	datafile := models.Datafile{
+		Meta: models.Meta{RegionID: "rudy", Featured: []string{"kosciol", "zamek"}},
+		Sections: []models.Section{
+			{ID: "zabytki", Places: []models.Place{
+				{ID: "kosciol", Section: "zabytki"},
+				{ID: "palac", Section: "przyroda"},
+			}},
+			{ID: "przyroda", Places: []models.Place{
+				{ID: "staw", Section: "natura"},
+			}},
+		},
+		Tracks:  []models.Track{{ID: "szlak"}},
+		Stories: []models.Story{{ID: "palac"}},
+	}
+
+	got := make([]string, 0)
+	for _, problem := range datafile.CheckReferences() {
+		got = append(got, string(problem.Kind)+" "+problem.Error())
+	}
+
+	want := []string{
+		"duplicate story palac: id: id is already used by place",
+		`dangling meta rudy: featured: place zamek does not exist`,
+		`mismatched place palac: section: is "przyroda", but the place is in section zabytki`,
+		`dangling place staw: section: is "natura", but the place is in section przyroda`,
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("got problems:\n%s", cmp.Diff(want, got))
+	}
+
+	index := datafile.IndexIDs()
+	if got := index.PlaceSections["staw"].ID; got != "przyroda" {
+		t.Errorf("got section %s of place staw, want przyroda", got)
+	}
+}
+
+func TestCheckReferencesExample(t *testing.T) {
+	datafile, err := models.ParseDatafile(sourcetest.Datafile(), models.Compressed, 1, false)
+	if err != nil {
+		t.Fatalf("failed to parse datafile: %v", err)
+	}
+
+	if err := models.ReferencesError(datafile.CheckReferences()); err != nil {
+		t.Errorf("example datafile has broken references:\n%v", err)
+	}
+}
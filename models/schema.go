@@ -0,0 +1,14 @@
+package models
+
+import "github.com/opentouristics/database-tools/jsonschema"
+
+// DatafileSchema returns JSON schema of data.json file. It is generated from
+// the Datafile struct, so it can never be out of date.
+func DatafileSchema() *jsonschema.Schema {
+	schema := jsonschema.Generate(Datafile{})
+	schema.ID = "datafile"
+	schema.Dialect = jsonschema.Draft
+	schema.Title = "Generated data"
+
+	return schema
+}
@@ -3,28 +3,35 @@ package models
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
+	"io/fs"
+	"path"
 
 	"github.com/opentouristics/database-tools/formatters"
+	"github.com/opentouristics/database-tools/parallel"
 	"github.com/opentouristics/database-tools/readers"
 )
 
 // Section represents places of similiar type and associated metadata.
 type Section struct {
-	ID        string  `json:"id"`
-	Name      Text    `json:"name"`
-	BgImage   string  `json:"background_image"`
-	QuickInfo Text    `json:"quick_info"`
-	Places    []Place `json:"places"`
+	ID        string  `json:"id" description:"Unique ID of the section in the datafile."`
+	Name      Text    `json:"name" description:"Localized name of the section."`
+	BgImage   string  `json:"background_image" description:"Filename of the section's background image."`
+	QuickInfo Text    `json:"quick_info" description:"Short and brief description of the section."`
+	Places    []Place `json:"places" description:"Places in the section."`
+
+	bgImagePath string
 }
 
-// Parse parses section data from its directory and assigns
-// it to section pointed to by s. It must be used directly
-// in the scetions's directory. It recursively parses places.
-func (section *Section) Parse(verbose bool) error {
-	data, err := readers.ReadFromFile("data.json")
+// Parse parses section data from directory dir of fsys and assigns it to
+// section pointed to by s. It recursively parses places (with images in
+// quality), using at most jobs goroutines at once.
+func (section *Section) Parse(fsys fs.FS, dir string, quality Quality, jobs int, verbose bool) error {
+	sectionFS, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	data, err := fs.ReadFile(sectionFS, "data.json")
 	if err != nil {
 		return err
 	}
@@ -33,49 +40,54 @@ func (section *Section) Parse(verbose bool) error {
 		return err
 	}
 
-	name, err := readers.ReadLocalizedFiles("name.txt")
+	name, err := readers.ReadLocalizedFiles(sectionFS, "name.txt")
 	if err != nil {
 		return err
 	}
 	section.Name = formatters.ToContent(name)
 
-	quickInfo, err := readers.ReadLocalizedFiles("quick_info.txt")
+	quickInfo, err := readers.ReadLocalizedFiles(sectionFS, "quick_info.txt")
 	if err != nil {
 		return err
 	}
 	section.QuickInfo = formatters.ToContent(quickInfo)
 
-	// Parse places.
-	places := make([]Place, 0, 50)
-	placesWalker := func(path string, _ os.FileInfo, err error) error {
+	if section.BgImage != "" {
+		section.bgImagePath, err = FindImage(fsys, dir, quality, section.BgImage)
 		if err != nil {
-			return fmt.Errorf("start walk place %#v: %w", path, err)
-		}
-
-		level := strings.Count(path, "/")
-		if level != 1 {
-			return nil
+			return fmt.Errorf("background image: %w", err)
 		}
+	}
 
-		os.Chdir(path)
+	// Parse places.
+	placeDirs, err := subdirs(fsys, path.Join(dir, "places"))
+	if err != nil {
+		return fmt.Errorf("read places: %w", err)
+	}
 
-		var place Place
-		err = place.Parse(verbose)
+	places := make([]Place, len(placeDirs))
+	err = parallel.ForEach(len(placeDirs), jobs, func(i int) error {
+		err := places[i].Parse(fsys, placeDirs[i], quality, verbose)
 		if err != nil {
-			return fmt.Errorf("parse %s: %w", path, err)
+			return fmt.Errorf("parse %s: %w", placeDirs[i], err)
 		}
-		os.Chdir("../..")
 
-		places = append(places, place)
 		return nil
-	}
-
-	err = filepath.Walk("places", placesWalker)
+	})
 	if err != nil {
-		return fmt.Errorf("walk places: %w", err)
+		return err
 	}
 
 	section.Places = places
 
 	return nil
 }
+
+// Assets returns files referenced by the section itself, without its places.
+func (section *Section) Assets() []Asset {
+	if section.bgImagePath == "" {
+		return make([]Asset, 0)
+	}
+
+	return imageAssets("section "+section.ID, []string{section.bgImagePath})
+}
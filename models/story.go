@@ -3,8 +3,9 @@ package models
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"path"
+	"sort"
 
 	"github.com/opentouristics/database-tools/formatters"
 	"github.com/opentouristics/database-tools/readers"
@@ -12,25 +13,32 @@ import (
 
 // Story represents a longer piece of text about a particular topic.
 type Story struct {
-	ID           string `json:"id"`
-	Name         Text   `json:"name"`
-	MarkdownFile string `json:"markdown_filename"`
-	markdownPath string
-	Images       []string `json:"images"`
-	imagePaths   []string
+	ID           string `json:"id" description:"Unique ID of the story."`
+	Name         Text   `json:"name" description:"Localized name of the story."`
+	MarkdownFile string `json:"markdown_filename" description:"Name of the markdown file with contents of the story."`
+	// Maps language code to the name of the markdown file in that language.
+	// It's not a Text, because filenames aren't translated.
+	MarkdownFiles map[string]string `json:"markdown_files" description:"Filenames of markdown files with contents of the story, keyed by language."`
+	markdownPaths map[string]string
+	Images        []Image `json:"images" description:"Images that are referenced from the markdown file."`
+	imagePaths    []string
 }
 
-// Parse parses story data from its directory and assigns
-// it to story pointed to by s. It must be used directly
-// in the tracks's directory.
-func (s *Story) Parse() error {
-	name, err := readers.ReadLocalizedFiles("name.txt")
+// Parse parses story data from directory dir of fsys and assigns it to story
+// pointed to by s. Paths of images in quality are collected.
+func (s *Story) Parse(fsys fs.FS, dir string, quality Quality) error {
+	storyFS, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	name, err := readers.ReadLocalizedFiles(storyFS, "name.txt")
 	if err != nil {
 		return err
 	}
 	s.Name = formatters.ToContent(name)
 
-	data, err := readers.ReadFromFile("data.json")
+	data, err := fs.ReadFile(storyFS, "data.json")
 	if err != nil {
 		return err
 	}
@@ -40,13 +48,13 @@ func (s *Story) Parse() error {
 		return err
 	}
 
-	err = s.makeMarkdownPath()
+	err = s.makeMarkdownPaths(fsys, dir)
 	if err != nil {
-		return fmt.Errorf("make markdown path: %w", err)
+		return fmt.Errorf("make markdown paths: %w", err)
 	}
 
 	s.imagePaths = make([]string, 0)
-	err = s.makeImagePaths(Compressed)
+	err = s.makeImagePaths(fsys, dir, quality)
 	if err != nil {
 		return fmt.Errorf("make images paths: %w", err)
 	}
@@ -54,51 +62,99 @@ func (s *Story) Parse() error {
 	return nil
 }
 
-func (s *Story) makeMarkdownPath() error {
-	wd, err := os.Getwd()
+// makeMarkdownPaths finds the story's markdown file in every available
+// language. It must exist in at least one of them.
+func (s *Story) makeMarkdownPaths(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, path.Join(dir, "content"))
 	if err != nil {
-		return fmt.Errorf("get working dir: %w", err)
+		return err
 	}
 
-	// FIXME: use markdown path for the correct language
-	s.markdownPath = filepath.Join(wd, "content", "pl", s.MarkdownFile+".md")
-	return nil
-}
-
-func (s *Story) makeImagePaths(quality Quality) error {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("get working dir: %w", err)
+	s.markdownPaths = make(map[string]string)
+	s.MarkdownFiles = make(map[string]string)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		lang := entry.Name()
+		markdownPath := path.Join(dir, "content", lang, s.MarkdownFile+".md")
+		if _, err := fs.Stat(fsys, markdownPath); err != nil {
+			continue
+		}
+
+		s.markdownPaths[lang] = markdownPath
+		s.MarkdownFiles[lang] = LocalizedMarkdownFile(s.MarkdownFile, lang)
 	}
 
-	var qualityDir string
-	if quality == Compressed {
-		qualityDir = "compressed"
-	} else if quality == Original {
-		qualityDir = "original"
+	if len(s.markdownPaths) == 0 {
+		return fmt.Errorf("no translation of %s.md in any language", s.MarkdownFile)
 	}
 
+	return nil
+}
+
+// LocalizedMarkdownFile returns name under which markdown file of a story in
+// lang is stored in the generated directory.
+func LocalizedMarkdownFile(markdownFile string, lang string) string {
+	return markdownFile + "." + lang + ".md"
+}
+
+func (s *Story) makeImagePaths(fsys fs.FS, dir string, quality Quality) error {
 	// s.Images were set when the story was parsed from its JSON.
 	if s.Images == nil {
-		s.Images = make([]string, 0)
+		s.Images = make([]Image, 0)
 	}
 
-	for _, image := range s.Images {
-		absPath := filepath.Join(cwd, "images/", qualityDir, "/"+image+".webp")
-		s.imagePaths = append(s.imagePaths, absPath)
+	imagePaths, err := findImages(fsys, dir, quality, s.Images)
+	if err != nil {
+		return err
 	}
+	s.imagePaths = append(s.imagePaths, imagePaths...)
 
 	return nil
 }
 
-// ImagePaths returns paths of all images of story s. They are
-// specific to your machine!
+// ImagePaths returns paths of all images of story s. They are relative to the
+// root of the datafile's file system.
 func (s *Story) ImagePaths() []string {
 	return s.imagePaths
 }
 
-// MarkdownPath returns path to the story's markdown file.
-// It is specific to your machine.
+// MarkdownPath returns path to the story's Polish markdown file, or an empty
+// string if there's none. It is relative to the root of the datafile's file
+// system.
 func (s *Story) MarkdownPath() string {
-	return s.markdownPath
+	return s.markdownPaths["pl"]
+}
+
+// Assets returns files referenced by story s: its images and markdown files in
+// every language.
+func (s *Story) Assets() []Asset {
+	owner := "story " + s.ID
+	assets := imageAssets(owner, s.imagePaths)
+
+	// Polish markdown is also copied under its old name, for app versions which
+	// don't know about markdown_files.
+	if s.MarkdownPath() != "" {
+		assets = append(assets, Asset{Owner: owner, Path: s.MarkdownPath(), Dir: StoriesDir, Name: s.MarkdownFile + ".md"})
+	}
+
+	langs := make([]string, 0, len(s.markdownPaths))
+	for lang := range s.markdownPaths {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	for _, lang := range langs {
+		assets = append(assets, Asset{Owner: owner, Path: s.markdownPaths[lang], Dir: StoriesDir, Name: s.MarkdownFiles[lang]})
+	}
+
+	return assets
+}
+
+// MarkdownPaths returns paths to the story's markdown files, keyed by language.
+// They are relative to the root of the datafile's file system.
+func (s *Story) MarkdownPaths() map[string]string {
+	return s.markdownPaths
 }
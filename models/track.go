@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"io/fs"
 
 	"github.com/opentouristics/database-tools/formatters"
 	"github.com/opentouristics/database-tools/readers"
@@ -9,42 +10,73 @@ import (
 
 // Track represents a bike trail or some other "long" geographical object.
 type Track struct {
-	ID        string     `json:"id"`
-	Name      Text       `json:"name"`
-	QuickInfo Text       `json:"quick_info"`
+	ID        string     `json:"id" description:"Unique ID of the trail."`
+	Name      Text       `json:"name" description:"Localized name of the trail."`
+	QuickInfo Text       `json:"quick_info" description:"Short and brief description of the trail."`
 	Overview  Text       `json:"overview"`
 	Images    []string   `json:"images"`
-	Coords    []Location `json:"coords"`
+	Coords    []Location `json:"coords" description:"Coordinates that the trail consists of."`
+
+	imagePaths []string
 }
 
-// Parse parses track data from its directory and assigns
-// it to track pointed to by t. It must be used directly
-// in the track's directory, usually by using os.Chdir().
-func (t *Track) Parse() error {
-	name, err := readers.ReadLocalizedFiles("name.txt")
+// Parse parses track data from directory dir of fsys and assigns it to track
+// pointed to by t. Paths of images in quality are collected.
+func (t *Track) Parse(fsys fs.FS, dir string, quality Quality) error {
+	trackFS, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	name, err := readers.ReadLocalizedFiles(trackFS, "name.txt")
 	if err != nil {
 		return err
 	}
 	t.Name = formatters.ToContent(name)
 
-	overview, err := readers.ReadLocalizedFiles("overview.txt")
+	overview, err := readers.ReadLocalizedFiles(trackFS, "overview.txt")
 	if err != nil {
 		return err
 	}
 	t.Overview = formatters.ToContent(overview)
 
-	quickInfo, err := readers.ReadLocalizedFiles("quick_info.txt")
+	quickInfo, err := readers.ReadLocalizedFiles(trackFS, "quick_info.txt")
 	if err != nil {
 		return err
 	}
 	t.QuickInfo = formatters.ToContent(quickInfo)
 
-	data, err := readers.ReadFromFile("data.json")
+	data, err := fs.ReadFile(trackFS, "data.json")
 	if err != nil {
 		return err
 	}
 
 	err = json.Unmarshal(data, t)
+	if err != nil {
+		return err
+	}
+
+	if t.Images == nil {
+		t.Images = make([]string, 0)
+	}
+	if t.Coords == nil {
+		t.Coords = make([]Location, 0)
+	}
+
+	t.imagePaths = make([]string, 0, len(t.Images))
+	for _, image := range t.Images {
+		imagePath, err := FindImage(fsys, dir, quality, image)
+		if err != nil {
+			return err
+		}
+
+		t.imagePaths = append(t.imagePaths, imagePath)
+	}
+
+	return nil
+}
 
-	return err
+// Assets returns files referenced by track t.
+func (t *Track) Assets() []Asset {
+	return imageAssets("track "+t.ID, t.imagePaths)
 }
@@ -0,0 +1,38 @@
+// Package parallel provides a tiny bounded worker pool used to speed up
+// parsing and generating big datafiles.
+package parallel
+
+import (
+	"errors"
+	"sync"
+)
+
+// ForEach calls fn for every index in [0, n), running at most jobs calls at
+// the same time. It waits for all calls to finish, even if some of them fail.
+//
+// Errors are joined in the order of indices, not in the order in which they
+// happened, so the result is deterministic. To keep the output deterministic,
+// fn should store its result at index i of a preallocated slice.
+func ForEach(n int, jobs int, fn func(i int) error) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	errs := make([]error, n)
+	sem := make(chan struct{}, jobs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,65 @@
+package parallel_test
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/opentouristics/database-tools/parallel"
+)
+
+func TestForEach(t *testing.T) {
+	t.Run("respects the limit", func(t *testing.T) {
+		var running, maxRunning atomic.Int32
+		results := make([]int, 100)
+
+		err := parallel.ForEach(len(results), 4, func(i int) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+
+			for {
+				max := maxRunning.Load()
+				if n <= max || maxRunning.CompareAndSwap(max, n) {
+					break
+				}
+			}
+
+			results[i] = i * i
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := maxRunning.Load(); got > 4 {
+			t.Errorf("got %d concurrent calls, want at most 4", got)
+		}
+
+		for i, result := range results {
+			if result != i*i {
+				t.Errorf("got result %d at index %d, want %d", result, i, i*i)
+			}
+		}
+	})
+
+	t.Run("reports every error", func(t *testing.T) {
+		errOdd := errors.New("odd")
+
+		err := parallel.ForEach(10, 3, func(i int) error {
+			if i%2 == 1 {
+				return fmt.Errorf("item %d: %w", i, errOdd)
+			}
+			return nil
+		})
+
+		if !errors.Is(err, errOdd) {
+			t.Fatalf("got error %v, want %v", err, errOdd)
+		}
+
+		want := "item 1: odd\nitem 3: odd\nitem 5: odd\nitem 7: odd\nitem 9: odd"
+		if err.Error() != want {
+			t.Errorf("got error %q, want %q", err.Error(), want)
+		}
+	})
+}
@@ -1,10 +1,14 @@
 package readers
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
+	"sort"
+	"strings"
 )
 
 // ReadFromFile opens and reads from file at filepath. It gracefully handles
@@ -26,38 +30,74 @@ func ReadFromFile(filepath string) ([]byte, error) {
 
 // ReadLocalizedFiles reads contents of filename in all available languages.
 //
-// It lists names of directories in cwd, and then reads contents of filename in
-// every of these directories.
-func ReadLocalizedFiles(filename string) (map[string]string, error) {
-	dirs, err := os.ReadDir("content")
+// It lists names of directories in the "content" directory of fsys, and then
+// reads contents of filename in every of these directories. Languages in which
+// filename doesn't exist are skipped, but it must exist in at least one of
+// them. Which languages are required is decided by the datafile's language
+// policy.
+func ReadLocalizedFiles(fsys fs.FS, filename string) (map[string]string, error) {
+	dirs, err := fs.ReadDir(fsys, "content")
 	if err != nil {
 		return nil, fmt.Errorf("read localized files: %v", err)
 	}
 
 	contents := make(map[string]string)
 	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
 		lang := dir.Name()
-		filepath := filepath.Join("content", lang, filename)
-		content, err := ReadFromFile(filepath)
+		filePath := path.Join("content", lang, filename)
+		content, err := fs.ReadFile(fsys, filePath)
 		if err != nil {
-			fmt.Printf("error reading file: %v\n", err)
-			continue // it's okay
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("read %s: %v", filePath, err)
 		}
 
 		contents[lang] = string(content)
 	}
 
-	_, isEnglish := contents["en"]
-	_, isPolish := contents["pl"]
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("no translation of %s in any language", filename)
+	}
+
+	return contents, nil
+}
+
+// LocalizedFileNames returns sorted names of files starting with prefix which
+// exist in any language in the "content" directory of fsys.
+func LocalizedFileNames(fsys fs.FS, prefix string) ([]string, error) {
+	dirs, err := fs.ReadDir(fsys, "content")
+	if err != nil {
+		return nil, fmt.Errorf("read localized files: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
 
-	if !isEnglish && !isPolish {
-		fmt.Println("no english nor polish for", filename)
-		return nil, err
+		entries, err := fs.ReadDir(fsys, path.Join("content", dir.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read localized files: %v", err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+				seen[entry.Name()] = true
+			}
+		}
 	}
 
-	if _, ok := contents["pl"]; !ok {
-		return nil, fmt.Errorf("no pl translation for %s", filename)
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	return contents, nil
+	return names, nil
 }
@@ -0,0 +1,215 @@
+// Package search implements building the offline full-text search index which
+// is shipped inside of the generated datafile.
+//
+// There's one index per language. Words are lowercased and Polish diacritics
+// are folded (ą→a, ł→l), both when the index is built and when it's queried,
+// so that users can search without typing them.
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/opentouristics/database-tools/models"
+)
+
+// Weights of words depending on the field they come from. Matches in names
+// rank higher than matches in longer texts.
+const (
+	NameWeight      = 8
+	QuickInfoWeight = 3
+	OverviewWeight  = 2
+	ContentWeight   = 1
+)
+
+// minWordLength is the length of the shortest indexed word.
+const minWordLength = 2
+
+// Dir is the directory of the generated datafile which contains indexes.
+const Dir = "search"
+
+// Filename returns name of the file with the index in lang.
+func Filename(lang string) string {
+	return lang + ".json"
+}
+
+// Doc is a single searchable entity.
+type Doc struct {
+	Type string `json:"type"` // "place", "track" or "story".
+	ID   string `json:"id"`
+}
+
+// Index is an inverted index of texts of a datafile in a single language.
+//
+// Terms are sorted, so that terms starting with a prefix can be found with
+// binary search. Postings[i] lists documents containing Terms[i] as flattened
+// pairs of document index and score: [doc, score, doc, score, ...].
+type Index struct {
+	Lang     string   `json:"lang"`
+	Docs     []Doc    `json:"docs"`
+	Terms    []string `json:"terms"`
+	Postings [][]int  `json:"postings"`
+}
+
+// Build indexes places, tracks and stories of datafile d. Their texts are taken
+// in the first of langs they are available in (see models.Text.Localized), and
+// the index is labeled with langs[0].
+func Build(d *models.Datafile, langs ...string) Index {
+	ix := Index{Lang: langs[0], Docs: make([]Doc, 0), Terms: make([]string, 0), Postings: make([][]int, 0)}
+
+	scores := make(map[string]map[int]int)
+	add := func(doc int, text models.Text, weight int) {
+		for _, word := range Tokenize(text.Localized(langs...)) {
+			if scores[word] == nil {
+				scores[word] = make(map[int]int)
+			}
+			scores[word][doc] += weight
+		}
+	}
+
+	for _, place := range d.AllPlaces() {
+		doc := len(ix.Docs)
+		ix.Docs = append(ix.Docs, Doc{Type: "place", ID: place.ID})
+
+		add(doc, place.Name, NameWeight)
+		add(doc, place.QuickInfo, QuickInfoWeight)
+		add(doc, place.Overview, OverviewWeight)
+		for i := range place.Headers {
+			add(doc, place.Headers[i], ContentWeight)
+		}
+		for i := range place.Content {
+			add(doc, place.Content[i], ContentWeight)
+		}
+	}
+
+	for _, track := range d.Tracks {
+		doc := len(ix.Docs)
+		ix.Docs = append(ix.Docs, Doc{Type: "track", ID: track.ID})
+
+		add(doc, track.Name, NameWeight)
+		add(doc, track.QuickInfo, QuickInfoWeight)
+		add(doc, track.Overview, OverviewWeight)
+	}
+
+	for _, story := range d.Stories {
+		doc := len(ix.Docs)
+		ix.Docs = append(ix.Docs, Doc{Type: "story", ID: story.ID})
+
+		add(doc, story.Name, NameWeight)
+	}
+
+	for term := range scores {
+		ix.Terms = append(ix.Terms, term)
+	}
+	sort.Strings(ix.Terms)
+
+	for _, term := range ix.Terms {
+		docs := make([]int, 0, len(scores[term]))
+		for doc := range scores[term] {
+			docs = append(docs, doc)
+		}
+		sort.Ints(docs)
+
+		postings := make([]int, 0, 2*len(docs))
+		for _, doc := range docs {
+			postings = append(postings, doc, scores[term][doc])
+		}
+		ix.Postings = append(ix.Postings, postings)
+	}
+
+	return ix
+}
+
+// Result is a document matching a query.
+type Result struct {
+	Doc   Doc
+	Score int
+}
+
+// Search returns documents containing every word of query, best first. Words
+// of the query match terms they are a prefix of, and exact matches count
+// twice. It is the reference of how the app uses the index.
+func (ix *Index) Search(query string) []Result {
+	var total map[int]int
+	for _, word := range Tokenize(query) {
+		matched := make(map[int]int)
+
+		i := sort.SearchStrings(ix.Terms, word)
+		for ; i < len(ix.Terms) && strings.HasPrefix(ix.Terms[i], word); i++ {
+			multiplier := 1
+			if ix.Terms[i] == word {
+				multiplier = 2
+			}
+
+			postings := ix.Postings[i]
+			for j := 0; j+1 < len(postings); j += 2 {
+				matched[postings[j]] = max(matched[postings[j]], multiplier*postings[j+1])
+			}
+		}
+
+		if total == nil {
+			total = matched
+			continue
+		}
+
+		for doc := range total {
+			if _, ok := matched[doc]; !ok {
+				delete(total, doc)
+			} else {
+				total[doc] += matched[doc]
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(total))
+	for doc, score := range total {
+		results = append(results, Result{Doc: ix.Docs[doc], Score: score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Doc.ID < results[j].Doc.ID
+	})
+
+	return results
+}
+
+var folds = map[rune]string{
+	'ą': "a", 'ć': "c", 'ę': "e", 'ł': "l", 'ń': "n", 'ó': "o", 'ś': "s", 'ź': "z", 'ż': "z",
+	'ä': "a", 'ö': "o", 'ü': "u", 'ß': "ss",
+}
+
+// Fold lowercases s and replaces letters with diacritics with their base
+// letters.
+func Fold(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if fold, ok := folds[r]; ok {
+			b.WriteString(fold)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// Tokenize splits s into folded words. Words shorter than minWordLength are
+// skipped.
+func Tokenize(s string) []string {
+	fields := strings.FieldsFunc(Fold(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) >= minWordLength {
+			words = append(words, field)
+		}
+	}
+
+	return words
+}
@@ -0,0 +1,52 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opentouristics/database-tools/models"
+	"github.com/opentouristics/database-tools/search"
+)
+
+func TestTokenize(t *testing.T) {
+	got := search.Tokenize("Zażółć gęślą jaźń, w Łodzi!")
+	want := []string{"zazolc", "gesla", "jazn", "lodzi"}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("got words %q, want %q", got, want)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	datafile := models.Datafile{
+		Sections: []models.Section{{ID: "zabytki", Places: []models.Place{
+			{ID: "kosciol", Name: models.Text{"pl": "Kościół św. Józefa"}, QuickInfo: models.Text{"pl": "Gotycki kościół"}},
+			{ID: "palac", Name: models.Text{"pl": "Pałac"}, Overview: models.Text{"pl": "Obok stoi kościół"}},
+		}}},
+		Tracks: []models.Track{
+			{ID: "szlak", Name: models.Text{"pl": "Szlak"}, QuickInfo: models.Text{"en": "Trail around the palace"}},
+		},
+	}
+
+	index := search.Build(&datafile, "en", "pl")
+
+	ids := func(results []search.Result) []string {
+		got := make([]string, 0, len(results))
+		for _, result := range results {
+			got = append(got, result.Doc.ID)
+		}
+		return got
+	}
+
+	if got, want := ids(index.Search("kosc")), []string{"kosciol", "palac"}; !cmp.Equal(got, want) {
+		t.Errorf("got results %q for prefix, want %q", got, want)
+	}
+
+	if got, want := ids(index.Search("KOŚCIÓŁ józef")), []string{"kosciol"}; !cmp.Equal(got, want) {
+		t.Errorf("got results %q for two words, want %q", got, want)
+	}
+
+	if got, want := ids(index.Search("pal")), []string{"palac", "szlak"}; !cmp.Equal(got, want) {
+		t.Errorf("got results %q for text in the requested language, want %q", got, want)
+	}
+}
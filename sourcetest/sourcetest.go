@@ -0,0 +1,94 @@
+// Package sourcetest provides an example datafile source for tests of packages
+// which read datafile sources.
+package sourcetest
+
+import (
+	"bytes"
+	"image"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// File returns a file with content.
+func File(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+// WebP returns a WEBP image of size w x h.
+func WebP(w int, h int) *fstest.MapFile {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+
+	var buf bytes.Buffer
+	nativewebp.Encode(&buf, img, nil)
+
+	return &fstest.MapFile{Data: buf.Bytes()}
+}
+
+// Datafile returns source of datafile of region rudy, with texts in Polish and
+// some of them in English. It has a section with a single place and a story.
+// Tests modify it to get the cases they need.
+func Datafile() fstest.MapFS {
+	return fstest.MapFS{
+		"meta/data.json":           File(`{"region_id": "rudy", "featured": ["kosciol"]}`),
+		"meta/content/pl/name.txt": File("Rudy\n"),
+		"meta/content/en/name.txt": File("Rudy\n"),
+
+		"sections/01_zabytki/data.json":                 File(`{"id": "zabytki"}`),
+		"sections/01_zabytki/content/pl/name.txt":       File("Zabytki\n"),
+		"sections/01_zabytki/content/pl/quick_info.txt": File("Stare\nbudynki\n"),
+
+		"sections/01_zabytki/places/kosciol/data.json":                         File(`{"id": "kosciol", "section": "zabytki", "icon": "ic_kosciol", "lat": 50.2, "lng": 18.4, "images": ["kosciol_1"]}`),
+		"sections/01_zabytki/places/kosciol/content/pl/name.txt":               File("Kościół\n"),
+		"sections/01_zabytki/places/kosciol/content/en/name.txt":               File("Church\n"),
+		"sections/01_zabytki/places/kosciol/content/pl/quick_info.txt":         File("Gotycki\n"),
+		"sections/01_zabytki/places/kosciol/content/pl/overview.txt":           File("Bardzo\nstary\n"),
+		"sections/01_zabytki/places/kosciol/content/pl/text_1.txt":             File("Historia\n\nZbudowany\ndawno\n"),
+		"sections/01_zabytki/places/kosciol/content/pl/action_1.txt":           File("Strona\n"),
+		"sections/01_zabytki/places/kosciol/actions.json":                      File(`["https://example.com"]`),
+		"sections/01_zabytki/places/kosciol/images/compressed/kosciol_1.webp":  WebP(8, 6),
+		"sections/01_zabytki/places/kosciol/images/compressed/ic_kosciol.webp": File(""),
+		"sections/01_zabytki/places/kosciol/images/full/kosciol_1.webp":        WebP(32, 24),
+		"sections/01_zabytki/places/kosciol/images/full/ic_kosciol.webp":       File(""),
+		"sections/01_zabytki/places/kosciol/images/original/kosciol_1.jpg":     File(""),
+		"sections/01_zabytki/places/kosciol/images/original/ic_kosciol.jpg":    File(""),
+
+		"stories/01_legenda/data.json":                   File(`{"id": "legenda", "markdown_filename": "legenda", "images": ["smok"]}`),
+		"stories/01_legenda/content/pl/name.txt":         File("Legenda\n"),
+		"stories/01_legenda/content/pl/legenda.md":       File("# Legenda\n"),
+		"stories/01_legenda/content/en/name.txt":         File("Legend\n"),
+		"stories/01_legenda/content/en/legenda.md":       File("# Legend\n"),
+		"stories/01_legenda/images/compressed/smok.webp": WebP(6, 8),
+		"stories/01_legenda/images/full/smok.webp":       WebP(24, 32),
+		"stories/01_legenda/images/original/smok.heic":   File(""),
+	}
+}
+
+// Write writes files of fsys to directory dir on disk.
+func Write(t testing.TB, dir string, fsys fstest.MapFS) {
+	t.Helper()
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(filePath), 0o755)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(filePath, fsys[name].Data, 0o644)
+	})
+	if err != nil {
+		t.Fatalf("write datafile source to %s: %v", dir, err)
+	}
+}